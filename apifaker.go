@@ -3,11 +3,12 @@ package apifaker
 import (
 	"log"
 	"net/http"
+	"net/http/httputil"
 	"os"
 	"path/filepath"
-	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Focinfi/gtester"
@@ -24,22 +25,73 @@ type ApiFaker struct {
 	// Routers contains all routes use their name as the key
 	Routers map[string]*Router
 
+	// CustomRoutes the endpoints defined in json files contain only routes
+	CustomRoutes []*CustomRoute
+
 	// ExtMux the external mux for the real api
 	ExtMux http.Handler
 
 	// Prefix the prefix of fake apis
 	Prefix string
+
+	// Proxy records or replays the fake apis if it is not nil
+	Proxy *Proxy
+
+	// fallthroughProxy forwards the requests no fake api serves, see Fallthrough
+	fallthroughProxy *httputil.ReverseProxy
+
+	// Latency the default delay before handling requests of all resources
+	Latency     *Latency
+	latencyLock sync.RWMutex
+
+	faultsLock sync.RWMutex
+
+	// Auth authenticates requests if it is not nil, see SetAuth
+	Auth *Auth
+
+	// CORS allows cross-origin requests if it is not nil, see SetCORS
+	CORS *CORS
+
+	// JournalSize the max number of requests recorded in the journal, default 1000, negative disables the journal,
+	// see Requests
+	JournalSize int
+	journal     []JournalEntry
+	journalNext int
+	journalLock sync.Mutex
+
+	// ReadOnly rejects the POST, PUT, PATCH and DELETE requests of resources
+	ReadOnly bool
+
+	// InMemory keeps all changes in memory, SaveToFile will never write back to files,
+	// the files of "file" Stores are only kept untouched by NewWithApiDirInMemory which sets it before loading
+	InMemory bool
+
+	// closed stops the timer of SaveToFile
+	closed chan struct{}
 }
 
 // NewWithApiDir alloactes and returns a new ApiFaker with the given dir as its ApiDir,
 // the error will not be nil if
-//   1. dir is wrong
-//   2. json file format is wrong
-//   3. break rules described in README.md
+//  1. dir is wrong
+//  2. json file format is wrong
+//  3. break rules described in README.md
 func NewWithApiDir(dir string) (*ApiFaker, error) {
+	return newWithApiDir(dir, false)
+}
+
+// NewWithApiDirInMemory alloactes and returns a new InMemory ApiFaker like NewWithApiDir,
+// no file in dir will be written, the "file" Stores are restored from their files but keep changes in memory
+func NewWithApiDirInMemory(dir string) (*ApiFaker, error) {
+	return newWithApiDir(dir, true)
+}
+
+// newWithApiDir loads the api files in dir into a new ApiFaker
+func newWithApiDir(dir string, inMemory bool) (*ApiFaker, error) {
 	faker := &ApiFaker{
-		ApiDir:  dir,
-		Routers: map[string]*Router{},
+		ApiDir:   dir,
+		Routers:  map[string]*Router{},
+		InMemory: inMemory,
+		closed:   make(chan struct{}),
 	}
 
 	err := gtester.NewInspector().Check(func() error {
@@ -51,6 +103,13 @@ func NewWithApiDir(dir string) (*ApiFaker, error) {
 				return nil
 			}
 
+			if routes, ok, err := loadRoutesFile(path); err != nil {
+				return err
+			} else if ok {
+				faker.CustomRoutes = append(faker.CustomRoutes, routes...)
+				return nil
+			}
+
 			if router, err := NewRouterWithPath(path, faker); err != nil {
 				return err
 			} else {
@@ -65,6 +124,7 @@ func NewWithApiDir(dir string) (*ApiFaker, error) {
 	}).
 		Check(faker.CheckUniqueness).
 		Check(faker.CheckRelationships).
+		Check(faker.CheckRoutes).
 		Then(func() {
 			faker.setHandlers()
 			faker.setSaveToFileTimer()
@@ -94,17 +154,41 @@ func (af *ApiFaker) CheckRelationships() error {
 }
 
 // ServeHTTP implements the http.Handler.
-// It will use Engine when req.URL.Path hasing prefix of Prefix or ExtMux is nil
-// otherwise it will call ApiFaker.ExtMux.ServeHTTP()
+// It will use Engine when req.URL.Path hasing prefix of Prefix or ExtMux is nil,
+// and Proxy will be used instead of Engine for fake apis if Proxy is not nil,
+// otherwise it will call ApiFaker.ExtMux.ServeHTTP(),
+// the CORS headers are set and preflight requests are responded before using Engine or Proxy if CORS is not nil
 func (af *ApiFaker) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 	path := req.URL.Path
 	if af.Prefix == "" || strings.HasPrefix(path, af.Prefix+"/") || af.ExtMux == nil {
-		af.Engine.ServeHTTP(rw, req)
+		if af.CORS != nil && af.CORS.handle(rw, req, af) {
+			return
+		}
+
+		if af.Proxy != nil && !strings.HasPrefix(path, af.Prefix+adminPath+"/") {
+			af.Proxy.ServeHTTP(rw, req)
+		} else {
+			af.Engine.ServeHTTP(rw, req)
+		}
 	} else {
 		af.ExtMux.ServeHTTP(rw, req)
 	}
 }
 
+// routerOfPath returns the Router serves the given request path and its existence
+func (af *ApiFaker) routerOfPath(path string) (*Router, bool) {
+	if af.Prefix != "" {
+		if !strings.HasPrefix(path, af.Prefix+"/") {
+			return nil, false
+		}
+		path = strings.TrimPrefix(path, af.Prefix)
+	}
+
+	name := strings.SplitN(strings.TrimPrefix(path, "/"), "/", 2)[0]
+	router, ok := af.Routers[name]
+	return router, ok
+}
+
 // MountTo assign path as ApiFaker's Prefix and reset the handlers
 func (af *ApiFaker) MountTo(path string) {
 	af.Prefix = path
@@ -116,14 +200,56 @@ func (af *ApiFaker) IntegrateHandler(handler http.Handler) {
 	af.ExtMux = handler
 }
 
-// SaveToFile
-func (af *ApiFaker) SaveToFile() {
+// SaveToFile saves data of all Routers back to files,
+// it does nothing if InMemory is true, returns the first error
+func (af *ApiFaker) SaveToFile() error {
+	if af.InMemory {
+		return nil
+	}
+
+	var err error
+	for _, router := range af.Routers {
+		if saveErr := router.SaveToFile(); saveErr != nil && err == nil {
+			err = saveErr
+		}
+	}
+	return err
+}
+
+// DataChanged returns true if the data of any Model has changed since it was loaded or saved
+func (af *ApiFaker) DataChanged() bool {
+	for _, router := range af.Routers {
+		router.Model.RLock()
+		changed := router.Model.dataChanged
+		router.Model.RUnlock()
+		if changed {
+			return true
+		}
+	}
+	return false
+}
+
+// Close stops the timer of SaveToFile and closes the Stores of all Models,
+// call SaveToFile before Close to keep the changes
+func (af *ApiFaker) Close() error {
+	if af.closed != nil {
+		select {
+		case <-af.closed:
+		default:
+			close(af.closed)
+		}
+	}
+
+	var err error
 	for _, router := range af.Routers {
-		router.SaveToFile()
+		if closeErr := router.Model.Store.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
 	}
+	return err
 }
 
-// setSaveToFileTimer set a timer to call SaveToFile() once a day
+// setSaveToFileTimer set a timer to call SaveToFile() once a day until Close
 func (af *ApiFaker) setSaveToFileTimer() {
 	go func() {
 		for {
@@ -135,8 +261,13 @@ func (af *ApiFaker) setSaveToFileTimer() {
 				0, 0, 0, 0,
 				next.Location())
 			timer := time.NewTimer(nextSaveDate.Sub(now))
-			<-timer.C
-			af.SaveToFile()
+			select {
+			case <-timer.C:
+				af.SaveToFile()
+			case <-af.closed:
+				timer.Stop()
+				return
+			}
 		}
 	}()
 }
@@ -153,6 +284,12 @@ func (af *ApiFaker) setHandlers() {
 
 	// reset Engine
 	af.Engine = NewGinEngineWithFaker(af)
+	af.NoRoute(af.fallthroughHandler)
+	af.setFaultHandlers()
+	af.setRateLimitHandlers()
+	af.setJournalHandlers()
+	af.setCustomRouteHandlers()
+	af.setAuthHandlers()
 
 	for _, router := range af.Routers {
 		for _, route := range router.Routes {
@@ -165,16 +302,38 @@ func (af *ApiFaker) setHandlers() {
 					if id, ok := ctx.Get("idFloat64"); ok {
 						// GET /collection/:id
 						li, _ := model.Get(id.(float64))
-						newLi := li.InsertRelatedData(model)
-						ctx.JSON(http.StatusOK, newLi.ToMap())
+						item := model.representation(ctx, li)
+						setETag(ctx, item)
+						ctx.JSON(http.StatusOK, item)
 					} else {
 						// GET /collection
-						models := model.ToLineItems()
-						sort.Sort(models)
-						ctx.JSON(http.StatusOK, models.ToSlice())
+						conditions, err := NewConditionsWithGinContext(ctx, model)
+						if err != nil {
+							ctx.JSON(http.StatusBadRequest, ResponseErrorMsg(err))
+							return
+						}
+
+						lis := model.ownedOnly(ctx, model.Where(conditions))
+						if !withDeleted(ctx) {
+							lis = lis.withoutDeleted()
+						}
+						ctx.JSON(http.StatusOK, model.renderAll(model.readableAll(ctx, lis)))
 					}
 				})
 			case POST:
+				if strings.HasSuffix(path, "/restore") {
+					af.POST(path, func(ctx *gin.Context) {
+						id, _ := ctx.Get("idFloat64")
+						if li, err := model.Restore(id.(float64)); err != nil {
+							ctx.JSON(http.StatusBadRequest, ResponseErrorMsg(err))
+						} else {
+							setETag(ctx, model.representation(ctx, li))
+							ctx.JSON(http.StatusOK, model.render(model.transform(model.readable(ctx, li))))
+						}
+					})
+					continue
+				}
+
 				af.POST(path, func(ctx *gin.Context) {
 					li, err := NewLineItemWithGinContext(ctx, model)
 					if err == nil {
@@ -184,7 +343,8 @@ func (af *ApiFaker) setHandlers() {
 					if err != nil {
 						ctx.JSON(http.StatusBadRequest, ResponseErrorMsg(err))
 					} else {
-						ctx.JSON(http.StatusOK, li.ToMap())
+						setETag(ctx, model.representation(ctx, li))
+						ctx.JSON(http.StatusOK, model.render(model.transform(model.readable(ctx, li))))
 					}
 				})
 			case PUT:
@@ -199,10 +359,11 @@ func (af *ApiFaker) setHandlers() {
 
 					// update
 					id, _ := ctx.Get("idFloat64")
-					if err := model.Update(id.(float64), &newLi); err != nil {
-						ctx.JSON(http.StatusBadRequest, ResponseErrorMsg(err))
+					if err := model.update(id.(float64), &newLi, model.ifMatch(ctx)); err != nil {
+						ctx.JSON(statusOfUpdateError(err), ResponseErrorMsg(err))
 					} else {
-						ctx.JSON(http.StatusOK, newLi.ToMap())
+						setETag(ctx, model.representation(ctx, newLi))
+						ctx.JSON(http.StatusOK, model.render(model.transform(model.readable(ctx, newLi))))
 					}
 				})
 			case PATCH:
@@ -210,29 +371,57 @@ func (af *ApiFaker) setHandlers() {
 					// update with attrs, got error if attrs is not complete
 					id, _ := ctx.Get("idFloat64")
 					if li, err := model.UpdateWithAttrs(id.(float64), ctx); err != nil {
-						ctx.JSON(http.StatusBadRequest, ResponseErrorMsg(err))
+						ctx.JSON(statusOfUpdateError(err), ResponseErrorMsg(err))
 					} else {
-						ctx.JSON(http.StatusOK, li.ToMap())
+						setETag(ctx, model.representation(ctx, li))
+						ctx.JSON(http.StatusOK, model.render(model.transform(model.readable(ctx, li))))
 					}
 				})
 			case DELETE:
 				af.DELETE(path, func(ctx *gin.Context) {
 					// delete
 					id, _ := ctx.Get("idFloat64")
-					model.Delete(id.(float64))
-					ctx.JSON(http.StatusOK, nil)
+					if err := model.delete(id.(float64), model.ifMatch(ctx)); err != nil {
+						ctx.JSON(statusOfUpdateError(err), ResponseErrorMsg(err))
+					} else {
+						ctx.JSON(http.StatusOK, nil)
+					}
 				})
 			}
 		}
 	}
 }
 
+// ReadOnlyMiddleware returns a gin.HandlerFunc which responses 405
+// for POST, PUT, PATCH and DELETE requests of resources if ApiFaker.ReadOnly is true
+func ReadOnlyMiddleware(faker *ApiFaker) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !faker.ReadOnly || ctx.Request.Method == http.MethodGet {
+			return
+		}
+
+		if _, ok := faker.routerOfPath(ctx.Request.URL.Path); ok {
+			ctx.JSON(http.StatusMethodNotAllowed, map[string]string{"message": "apifaker is read-only"})
+			ctx.Abort()
+		}
+	}
+}
+
 // NewGinEngineWithFaker allocate and returns a new gin.Engine pointer,
-// added a new middleware which will check the type id param and the resource existence,
-// if ok, set the float64 value of id named idFloat64, otherwise response 404 or 400.
+// added the JournalMiddleware, the LatencyMiddleware, the FaultMiddleware, the RateLimitMiddleware, the AuthMiddleware, the PermissionMiddleware, the ReadOnlyMiddleware and a new middleware which will check the type id param and the resource existence,
+// if ok, set the float64 value of id named idFloat64, otherwise response 404 or 400 and abort,
+// soft deleted items are not found unless the request restores them or gets them with with_deleted=true,
+// then the OwnerMiddleware and the ConditionalMiddleware are added.
 func NewGinEngineWithFaker(faker *ApiFaker) *gin.Engine {
 	engine := gin.Default()
 	gin.SetMode(gin.ReleaseMode)
+	engine.Use(JournalMiddleware(faker))
+	engine.Use(LatencyMiddleware(faker))
+	engine.Use(FaultMiddleware(faker))
+	engine.Use(RateLimitMiddleware(faker))
+	engine.Use(AuthMiddleware(faker))
+	engine.Use(PermissionMiddleware(faker))
+	engine.Use(ReadOnlyMiddleware(faker))
 	// check id
 	engine.Use(func(ctx *gin.Context) {
 		// check if param "id" is int
@@ -244,20 +433,28 @@ func NewGinEngineWithFaker(faker *ApiFaker) *gin.Engine {
 		id, err := strconv.ParseFloat(idStr, 64)
 		if err != nil {
 			ctx.JSON(http.StatusBadRequest, ResponseErrorMsg(err))
+			ctx.Abort()
+			return
 		}
 
-		path := strings.TrimSuffix(ctx.Request.URL.Path, "/")
-		pathPieces := strings.Split(path, "/")
-		resourceName := pathPieces[len(pathPieces)-2]
+		if router, ok := faker.routerOfPath(ctx.Request.URL.Path); ok {
+			// soft deleted item can only be restored or got with with_deleted=true
+			li, ok := router.Model.Get(id)
+			if ok && li.isDeleted() {
+				restoring := ctx.Request.Method == http.MethodPost && strings.HasSuffix(ctx.Request.URL.Path, "/restore")
+				ok = restoring || (ctx.Request.Method == http.MethodGet && withDeleted(ctx))
+			}
 
-		if router, ok := faker.Routers[resourceName]; ok {
-			if _, ok := router.Model.Get(id); ok {
+			if ok {
 				ctx.Set("idFloat64", id)
 			} else {
 				ctx.JSON(http.StatusNotFound, nil)
+				ctx.Abort()
 			}
 		}
 	})
+	engine.Use(OwnerMiddleware(faker))
+	engine.Use(ConditionalMiddleware(faker))
 
 	return engine
 }
@@ -6,6 +6,7 @@ import (
 	"github.com/Focinfi/gtester/httpmock"
 	. "github.com/smartystreets/goconvey/convey"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
 )
@@ -119,6 +120,22 @@ func TestApiFaker(t *testing.T) {
 			})
 		})
 
+		Describ("GET /books?user_id=:user_id", func() {
+			Context("when pass a valid user_id", func() {
+				response := httpmock.GET("/books?user_id=1", nil)
+				It("returns books of the user", func() {
+					Expect(response, shouldHasJsonResponse, usersFixture[0]["books"])
+				})
+			})
+
+			Context("when pass a invalid user_id", func() {
+				response := httpmock.GET("/books?user_id=xxx", nil)
+				It("returns 400", func() {
+					Expect(response.Code, ShouldEqual, http.StatusBadRequest)
+				})
+			})
+		})
+
 		Describ("POST /users", func() {
 			Context("when pass valid params", func() {
 				response, _ := httpmock.POSTForm("/users", userParam)
@@ -167,7 +184,7 @@ func TestApiFaker(t *testing.T) {
 		})
 
 		Describ("PUT /users/:id", func() {
-			userEditedAttrPut := map[string]interface{}{"id": float64(4), "name": "Vincent", "phone": "13213213217", "age": float64(23)}
+			userEditedAttrPut := map[string]interface{}{"id": float64(4), "name": "Vince", "phone": "13213213217", "age": float64(23)}
 			Context("when pass valid params", func() {
 				response, _ := httpmock.PUT("/users/4", userEditedAttrPut)
 				It("returns 200 and and the edited user", func() {
@@ -220,3 +237,50 @@ func TestApiFaker(t *testing.T) {
 		})
 	})
 }
+
+func TestDataChanged(t *testing.T) {
+	faker, _ := NewWithApiDir(testDir)
+	faker.InMemory = true
+
+	Describ("DataChanged", t, func() {
+		It("returns false until the data of a Model changes", func() {
+			Expect(faker.DataChanged(), ShouldBeFalse)
+			faker.Routers["users"].Model.Delete(3)
+			Expect(faker.DataChanged(), ShouldBeTrue)
+		})
+	})
+}
+
+func TestIdCheck(t *testing.T) {
+	faker, _ := NewWithApiDir(testDir)
+	faker.InMemory = true
+	request := func(path string) *httptest.ResponseRecorder {
+		rw := httptest.NewRecorder()
+		faker.ServeHTTP(rw, httptest.NewRequest("GET", path, nil))
+		return rw
+	}
+
+	Describ("id check", t, func() {
+		Context("when the path is nested", func() {
+			It("checks the item of the resource the path starts with", func() {
+				Expect(request("/users/1/books_count").Code, ShouldEqual, http.StatusOK)
+				Expect(request("/users/100/books_count").Code, ShouldEqual, http.StatusNotFound)
+			})
+
+			It("responses 400 and aborts if the id is not a number", func() {
+				response := request("/users/xxx/books_count")
+				Expect(response.Code, ShouldEqual, http.StatusBadRequest)
+				Expect(response.Body.String(), ShouldNotContainSubstring, "count")
+			})
+		})
+
+		Context("when mounted with a prefix", func() {
+			faker.MountTo("/fake_api")
+			It("checks the item of the resource after the prefix", func() {
+				Expect(request("/fake_api/users/1/books_count").Code, ShouldEqual, http.StatusOK)
+				Expect(request("/fake_api/users/100/books_count").Code, ShouldEqual, http.StatusNotFound)
+				Expect(request("/fake_api/users/100").Code, ShouldEqual, http.StatusNotFound)
+			})
+		})
+	})
+}
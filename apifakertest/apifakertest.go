@@ -0,0 +1,112 @@
+// Package apifakertest starts an isolated apifaker server for a test.
+//
+// New copies the api dir into a temp dir, keeps all changes in memory and serves the fake apis
+// with an httptest.Server, the server is closed when the test and its subtests finish:
+//
+//	func TestClient(t *testing.T) {
+//		server := apifakertest.New(t, "./fake_apis")
+//		client := NewClient(server.URL)
+//		server.Faker.Resource("users").Create(map[string]interface{}{"name": "Frank"})
+//	}
+//
+// Every call of New returns a new instance, so parallel subtests can call it to get their own data.
+package apifakertest
+
+import (
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Focinfi/apifaker"
+)
+
+// Server an httptest.Server serves the fake apis of Faker
+type Server struct {
+	*httptest.Server
+
+	// Faker serves the fake apis, use it to set faults, auth or data in Go
+	Faker *apifaker.ApiFaker
+}
+
+// New loads a copy of the api dir into an in-memory ApiFaker and starts an httptest.Server serves it,
+// the fixtures in dir are never changed, the server and the ApiFaker are closed by t.Cleanup,
+// the test fails immediately if the api files are invalid
+func New(t testing.TB, dir string) *Server {
+	t.Helper()
+
+	copied := t.TempDir()
+	if err := copyDir(dir, copied); err != nil {
+		t.Fatalf("apifakertest: can not copy %s: %v", dir, err)
+	}
+
+	faker, err := apifaker.NewWithApiDirInMemory(copied)
+	if err != nil {
+		t.Fatalf("apifakertest: %v", err)
+	}
+	return serve(t, faker)
+}
+
+// NewWithBuilder builds the resources defined in Go and starts an httptest.Server serves them,
+// so the fixtures can be kept inline in the test, the test fails immediately if the resources are invalid
+func NewWithBuilder(t testing.TB, builder *apifaker.Builder) *Server {
+	t.Helper()
+
+	faker, err := builder.Build()
+	if err != nil {
+		t.Fatalf("apifakertest: %v", err)
+	}
+	return serve(t, faker)
+}
+
+// serve starts an httptest.Server serves the faker, the server and the faker are closed by t.Cleanup
+func serve(t testing.TB, faker *apifaker.ApiFaker) *Server {
+	server := &Server{Server: httptest.NewServer(faker), Faker: faker}
+	t.Cleanup(func() {
+		server.Close()
+		faker.Close()
+	})
+	return server
+}
+
+// copyDir copies the files in src into dst recursively
+func copyDir(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		return copyFile(path, target)
+	})
+}
+
+// copyFile copies the file src into dst
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,71 @@
+package apifakertest
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"github.com/Focinfi/apifaker"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var It = Convey
+var Expect = So
+
+var testDir = filepath.Join("..", "api_static_test")
+
+func TestNew(t *testing.T) {
+	fixture, _ := ioutil.ReadFile(filepath.Join(testDir, "books.json"))
+
+	t.Run("serves the fake apis in memory", func(t *testing.T) {
+		server := New(t, testDir)
+
+		response, err := http.PostForm(server.URL+"/books", url.Values{"title": {"Dune"}, "user_id": {"1"}})
+		It("creates the book", t, func() {
+			Expect(err, ShouldBeNil)
+			Expect(response.StatusCode, ShouldEqual, http.StatusOK)
+			Expect(server.Faker.Resource("books").Count(), ShouldEqual, 4)
+		})
+
+		It("saves nothing into the fixtures", t, func() {
+			Expect(server.Faker.SaveToFile(), ShouldBeNil)
+			after, _ := ioutil.ReadFile(filepath.Join(testDir, "books.json"))
+			Expect(string(after), ShouldEqual, string(fixture))
+		})
+	})
+
+	for _, name := range []string{"first", "second"} {
+		t.Run("isolates "+name, func(t *testing.T) {
+			t.Parallel()
+			server := New(t, testDir)
+
+			_, err := server.Faker.Resource("books").Create(map[string]interface{}{"title": "Emma", "user_id": 2})
+			It("has its own data", t, func() {
+				Expect(err, ShouldBeNil)
+				Expect(server.Faker.Resource("books").Count(), ShouldEqual, 4)
+			})
+		})
+	}
+
+	t.Run("never changes the fixtures", func(t *testing.T) {
+		after, _ := ioutil.ReadFile(filepath.Join(testDir, "books.json"))
+		It("keeps the api files", t, func() {
+			Expect(string(after), ShouldEqual, string(fixture))
+		})
+	})
+}
+
+func TestNewWithBuilder(t *testing.T) {
+	server := NewWithBuilder(t, apifaker.New().
+		Resource("notes", apifaker.Columns{{Name: "id", Type: "number"}, {Name: "title", Type: "string"}}).
+		Seeds(map[string]interface{}{"id": 1, "title": "inline"}))
+
+	response, err := http.Get(server.URL + "/notes/1")
+	It("serves the inline resources", t, func() {
+		Expect(err, ShouldBeNil)
+		Expect(response.StatusCode, ShouldEqual, http.StatusOK)
+		Expect(server.Faker.Resource("notes").Count(), ShouldEqual, 1)
+	})
+}
@@ -0,0 +1,562 @@
+package apifaker
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// IdentityKey the key of the authenticated *Identity in gin.Context
+	IdentityKey = "apifaker.identity"
+
+	// tokenPath the path of the fake token endpoint
+	tokenPath = "/auth/token"
+
+	defaultApiKeyHeader = "X-Api-Key"
+	defaultUserResource = "users"
+	defaultTokenTTL     = 3600
+
+	defaultRefreshTokenTTL = 86400
+)
+
+// Identity the authenticated caller
+type Identity struct {
+	// Subject the id of the user or the name of the api key
+	Subject string `json:"sub"`
+
+	// Role the role of the caller, it is the value of RoleColumn for users
+	Role string `json:"role,omitempty"`
+
+	// Scopes the scopes granted to the caller
+	Scopes []string `json:"scopes,omitempty"`
+
+	// User the user item if the caller is a user of UserResource
+	User map[string]interface{} `json:"-"`
+
+	// Claims the claims of the JWT if the caller uses a JWT
+	Claims map[string]interface{} `json:"-"`
+}
+
+// Auth simulates the authentication of apis with api keys, basic auth and bearer tokens
+type Auth struct {
+	// ApiKeyHeader the header contains the api key, default "X-Api-Key"
+	ApiKeyHeader string `json:"api_key_header,omitempty"`
+
+	// ApiKeys maps api keys to their identities
+	ApiKeys map[string]*Identity `json:"api_keys,omitempty"`
+
+	// Basic accepts HTTP basic auth with the username and password of UserResource
+	Basic bool `json:"basic,omitempty"`
+
+	// Bearer accepts bearer tokens issued by POST /auth/token
+	Bearer bool `json:"bearer,omitempty"`
+
+	// JWT issues JWTs signed by a locally generated RS256 key instead of opaque bearer tokens,
+	// the JWKS and the OIDC discovery document are served at /.well-known/jwks.json and /.well-known/openid-configuration
+	JWT bool `json:"jwt,omitempty"`
+
+	// Issuer the iss claim of JWTs, default the base url of the request
+	Issuer string `json:"issuer,omitempty"`
+
+	// Audience the aud claim of JWTs, optional
+	Audience string `json:"audience,omitempty"`
+
+	// Claims maps the names of extra claims of JWTs to the columns of UserResource
+	Claims map[string]string `json:"claims,omitempty"`
+
+	// Scopes maps apis to the scopes they require, like {"POST books": ["books:write"]}
+	Scopes map[string][]string `json:"scopes,omitempty"`
+
+	// DefaultScopes the scopes granted when the token request has no scope param
+	DefaultScopes []string `json:"default_scopes,omitempty"`
+
+	// TokenTTL the seconds before a bearer token expires, default 3600
+	TokenTTL int `json:"token_ttl,omitempty"`
+
+	// RefreshTokenTTL the seconds before a refresh token expires, default 86400
+	RefreshTokenTTL int `json:"refresh_token_ttl,omitempty"`
+
+	// UserResource the resource of users, default "users"
+	UserResource string `json:"user_resource,omitempty"`
+
+	// UsernameColumn the column of username, default "name"
+	UsernameColumn string `json:"username_column,omitempty"`
+
+	// PasswordColumn the column of password, default "password"
+	PasswordColumn string `json:"password_column,omitempty"`
+
+	// RoleColumn the column of the role of users, optional
+	RoleColumn string `json:"role_column,omitempty"`
+
+	// DefaultPublic makes all apis public unless they are in Protected
+	DefaultPublic bool `json:"default_public,omitempty"`
+
+	// Public the apis without authentication, like "books", "GET books" or "GET *"
+	Public []string `json:"public,omitempty"`
+
+	// Protected the apis need authentication when DefaultPublic is true, in the same format of Public
+	Protected []string `json:"protected,omitempty"`
+
+	// InvalidStatus the status code for invalid credentials, 401(default) or 403,
+	// requests without credentials always get 401
+	InvalidStatus int `json:"invalid_status,omitempty"`
+
+	apiFaker *ApiFaker
+	key      *signingKey
+
+	// tokens and refreshTokens use the token as the key
+	tokens        map[string]*issuedToken
+	refreshTokens map[string]*issuedToken
+	tokensLock    sync.Mutex
+}
+
+// issuedToken a bearer token issued by the token endpoint
+type issuedToken struct {
+	identity  *Identity
+	expiresAt time.Time
+}
+
+// NewAuthWithPath allocates and returns a new Auth with the json file of the given path
+func NewAuthWithPath(path string) (*Auth, error) {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	auth := &Auth{}
+	if err := json.Unmarshal(bytes, auth); err != nil {
+		return nil, AuthErrorf("%s has wrong json format: %v", path, err)
+	}
+	return auth, nil
+}
+
+// SetAuth checks the Auth and uses it to authenticate requests,
+// a nil Auth makes all apis public
+func (af *ApiFaker) SetAuth(auth *Auth) error {
+	if auth != nil {
+		auth.apiFaker = af
+		if err := auth.CheckMeta(); err != nil {
+			return err
+		}
+	}
+
+	previous := af.Auth
+	af.Auth = auth
+	if err := af.CheckRoutes(); err != nil {
+		af.Auth = previous
+		return err
+	}
+	af.setHandlers()
+	return nil
+}
+
+// CheckMeta sets the default values and checks
+//  1. InvalidStatus must be 401 or 403
+//  2. every rule of Public, Protected and Scopes must be valid
+//  3. every owner_column of resources must refer to UserResource
+//  4. UserResource must exist with UsernameColumn, PasswordColumn, RoleColumn and the columns of Claims
+//     if Basic, Bearer or JWT is true
+//
+// a signing key is generated if JWT is true, which implies Bearer
+func (auth *Auth) CheckMeta() error {
+	if auth.ApiKeyHeader == "" {
+		auth.ApiKeyHeader = defaultApiKeyHeader
+	}
+	if auth.TokenTTL <= 0 {
+		auth.TokenTTL = defaultTokenTTL
+	}
+	if auth.RefreshTokenTTL <= 0 {
+		auth.RefreshTokenTTL = defaultRefreshTokenTTL
+	}
+	if auth.UserResource == "" {
+		auth.UserResource = defaultUserResource
+	}
+	if auth.UsernameColumn == "" {
+		auth.UsernameColumn = "name"
+	}
+	if auth.PasswordColumn == "" {
+		auth.PasswordColumn = "password"
+	}
+	if auth.InvalidStatus == 0 {
+		auth.InvalidStatus = http.StatusUnauthorized
+	}
+	auth.tokens = map[string]*issuedToken{}
+	auth.refreshTokens = map[string]*issuedToken{}
+	if auth.JWT {
+		auth.Bearer = true
+		if auth.key == nil {
+			key, err := newSigningKey()
+			if err != nil {
+				return err
+			}
+			auth.key = key
+		}
+	}
+
+	if auth.InvalidStatus != http.StatusUnauthorized && auth.InvalidStatus != http.StatusForbidden {
+		return AuthErrorf("invalid_status must be 401 or 403: %d", auth.InvalidStatus)
+	}
+
+	rules := append(append([]string{}, auth.Public...), auth.Protected...)
+	for rule := range auth.Scopes {
+		rules = append(rules, rule)
+	}
+	for _, rule := range rules {
+		if _, _, err := parseAuthRule(rule); err != nil {
+			return err
+		}
+	}
+
+	for _, router := range auth.apiFaker.Routers {
+		if model := router.Model; model.OwnerColumn != "" && model.ownerResource() != auth.UserResource {
+			return AuthErrorf("owner_column %s of resource %s must refer to %s", model.OwnerColumn, model.Name, auth.UserResource)
+		}
+	}
+
+	if !auth.Basic && !auth.Bearer {
+		return nil
+	}
+	model, err := auth.apiFaker.modelOf(auth.UserResource)
+	if err != nil {
+		return AuthErrorf("%v", err)
+	}
+	columns := []string{auth.UsernameColumn, auth.PasswordColumn, auth.RoleColumn}
+	for _, column := range auth.Claims {
+		columns = append(columns, column)
+	}
+	for _, name := range columns {
+		if name != "" && !model.hasColumn(name) {
+			return AuthErrorf("resource %s has no column %s", auth.UserResource, name)
+		}
+	}
+	return nil
+}
+
+// parseAuthRule parses a rule like "books", "GET books" or "GET *" into method and resource,
+// an empty method means all methods
+func parseAuthRule(rule string) (string, string, error) {
+	pieces := strings.Fields(rule)
+	switch len(pieces) {
+	case 1:
+		return "", pieces[0], nil
+	case 2:
+		method, err := ParseRestMethod(pieces[0])
+		if err != nil {
+			return "", "", AuthErrorf("wrong rule %s: %v", rule, err)
+		}
+		return method.String(), pieces[1], nil
+	}
+	return "", "", AuthErrorf("wrong rule %s, must be like \"books\", \"GET books\" or \"GET *\"", rule)
+}
+
+// matchRules returns if any rule matches the method and resource
+func matchRules(rules []string, method, resource string) bool {
+	for _, rule := range rules {
+		ruleMethod, ruleResource, err := parseAuthRule(rule)
+		if err != nil {
+			continue
+		}
+		if (ruleMethod == "" || ruleMethod == method) && (ruleResource == "*" || ruleResource == resource) {
+			return true
+		}
+	}
+	return false
+}
+
+// isPublic returns if the api of the method and resource needs no authentication
+func (auth *Auth) isPublic(method, resource string) bool {
+	if auth.DefaultPublic {
+		return !matchRules(auth.Protected, method, resource)
+	}
+	return matchRules(auth.Public, method, resource)
+}
+
+// authenticate returns the Identity of the request,
+// ok is false if the request has no credentials
+func (auth *Auth) authenticate(req *http.Request) (identity *Identity, ok bool) {
+	if key := req.Header.Get(auth.ApiKeyHeader); key != "" {
+		return auth.ApiKeys[key], true
+	}
+
+	authorization := req.Header.Get("Authorization")
+	if authorization == "" {
+		return nil, false
+	}
+
+	if username, password, isBasic := req.BasicAuth(); isBasic && auth.Basic {
+		return auth.authenticateUser(username, password), true
+	}
+
+	if token := strings.TrimPrefix(authorization, "Bearer "); token != authorization && auth.Bearer {
+		if auth.JWT {
+			return auth.identityOfJWT(req, token), true
+		}
+		return auth.identityOfToken(token), true
+	}
+	return nil, true
+}
+
+// authenticateUser returns the Identity of the user with the username and password
+func (auth *Auth) authenticateUser(username, password string) *Identity {
+	model, err := auth.apiFaker.modelOf(auth.UserResource)
+	if err != nil {
+		return nil
+	}
+
+	for _, li := range model.FindBy(auth.UsernameColumn, username).withoutDeleted() {
+		if value, ok := li.Get(auth.PasswordColumn); ok && fmt.Sprint(value) == password {
+			return auth.identityOfUser(li)
+		}
+	}
+	return nil
+}
+
+// identityOfUser returns the Identity of the user item
+func (auth *Auth) identityOfUser(li LineItem) *Identity {
+	identity := &Identity{Subject: fmt.Sprint(li.Id()), User: li.ToMap()}
+	if role, ok := li.Get(auth.RoleColumn); ok && auth.RoleColumn != "" {
+		identity.Role = fmt.Sprint(role)
+	}
+	return identity
+}
+
+// identityOfToken returns the Identity of the unexpired bearer token
+func (auth *Auth) identityOfToken(token string) *Identity {
+	auth.tokensLock.Lock()
+	defer auth.tokensLock.Unlock()
+
+	issued, ok := auth.tokens[token]
+	if !ok {
+		return nil
+	}
+	if time.Now().After(issued.expiresAt) {
+		delete(auth.tokens, token)
+		return nil
+	}
+	return issued.identity
+}
+
+// issueToken issues a new bearer token or JWT and a new refresh token for the Identity,
+// the expired tokens and refresh tokens are removed
+func (auth *Auth) issueToken(req *http.Request, identity *Identity) (map[string]interface{}, error) {
+	auth.tokensLock.Lock()
+	defer auth.tokensLock.Unlock()
+
+	now := time.Now()
+	for _, issued := range []map[string]*issuedToken{auth.tokens, auth.refreshTokens} {
+		for key, token := range issued {
+			if now.After(token.expiresAt) {
+				delete(issued, key)
+			}
+		}
+	}
+
+	token, refreshToken := randomToken(), randomToken()
+	if auth.JWT {
+		jwt, err := auth.signJWT(req, identity)
+		if err != nil {
+			return nil, err
+		}
+		token = jwt
+	} else {
+		auth.tokens[token] = &issuedToken{
+			identity:  identity,
+			expiresAt: now.Add(time.Duration(auth.TokenTTL) * time.Second),
+		}
+	}
+	auth.refreshTokens[refreshToken] = &issuedToken{
+		identity:  identity,
+		expiresAt: now.Add(time.Duration(auth.RefreshTokenTTL) * time.Second),
+	}
+
+	return map[string]interface{}{
+		"access_token":  token,
+		"token_type":    "Bearer",
+		"expires_in":    auth.TokenTTL,
+		"refresh_token": refreshToken,
+	}, nil
+}
+
+// useRefreshToken returns the Identity of the unexpired refresh token, the refresh token can be used only once
+func (auth *Auth) useRefreshToken(refreshToken string) *Identity {
+	auth.tokensLock.Lock()
+	defer auth.tokensLock.Unlock()
+
+	issued, ok := auth.refreshTokens[refreshToken]
+	if !ok {
+		return nil
+	}
+	delete(auth.refreshTokens, refreshToken)
+	if time.Now().After(issued.expiresAt) {
+		return nil
+	}
+	return issued.identity
+}
+
+// randomToken returns a random hex string
+func randomToken() string {
+	bytes := make([]byte, 16)
+	rand.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
+
+// tokenHandler handles POST /auth/token with grant_type "password" or "refresh_token",
+// the space separated scope param is granted, DefaultScopes is granted without it
+func (auth *Auth) tokenHandler(ctx *gin.Context) {
+	params := map[string]string{}
+	if strings.Contains(ctx.ContentType(), "json") {
+		// the fields can be numbers or booleans, like a numeric password
+		fields := map[string]interface{}{}
+		decoder := json.NewDecoder(ctx.Request.Body)
+		decoder.UseNumber()
+		if err := decoder.Decode(&fields); err != nil {
+			ctx.JSON(http.StatusBadRequest, map[string]string{"error": "invalid_request", "error_description": err.Error()})
+			return
+		}
+		for key, value := range fields {
+			if value != nil {
+				params[key] = fmt.Sprint(value)
+			}
+		}
+	} else {
+		for _, key := range []string{"grant_type", "username", "password", "refresh_token", "scope"} {
+			params[key] = ctx.PostForm(key)
+		}
+	}
+
+	var identity *Identity
+	switch params["grant_type"] {
+	case "", "password":
+		identity = auth.authenticateUser(params["username"], params["password"])
+	case "refresh_token":
+		identity = auth.useRefreshToken(params["refresh_token"])
+	default:
+		ctx.JSON(http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+
+	if identity == nil {
+		ctx.JSON(http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	if params["grant_type"] != "refresh_token" {
+		identity.Scopes = auth.DefaultScopes
+		if scope := params["scope"]; scope != "" {
+			identity.Scopes = strings.Fields(scope)
+		}
+	}
+
+	tokens, err := auth.issueToken(ctx.Request, identity)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ResponseErrorMsg(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, tokens)
+}
+
+// routes returns the routes of the token endpoint, the JWKS endpoint and the OIDC discovery endpoint
+// which are enabled, the paths are relative to Prefix
+func (auth *Auth) routes() []Route {
+	routes := []Route{}
+	if auth.Bearer {
+		routes = append(routes, Route{POST, tokenPath})
+	}
+	if auth.JWT {
+		routes = append(routes, Route{GET, jwksPath}, Route{GET, discoveryPath})
+	}
+	return routes
+}
+
+// setAuthHandlers sets the token endpoint if bearer tokens are enabled,
+// and the JWKS endpoint and the OIDC discovery endpoint if JWT is enabled
+func (af *ApiFaker) setAuthHandlers() {
+	if af.Auth != nil && af.Auth.Bearer {
+		af.POST(af.Prefix+tokenPath, af.Auth.tokenHandler)
+	}
+	if af.Auth != nil && af.Auth.JWT {
+		af.setJWTHandlers()
+	}
+}
+
+// IdentityOf returns the authenticated Identity of the request and its existence
+func IdentityOf(ctx *gin.Context) (*Identity, bool) {
+	value, ok := ctx.Get(IdentityKey)
+	if !ok {
+		return nil, false
+	}
+	identity, ok := value.(*Identity)
+	return identity, ok
+}
+
+// AuthMiddleware returns a gin.HandlerFunc which authenticates requests if ApiFaker.Auth is not nil,
+// the Identity is set into gin.Context with IdentityKey, protected apis response 401 without credentials,
+// InvalidStatus with invalid credentials and 403 without the required scopes
+func AuthMiddleware(faker *ApiFaker) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		auth := faker.Auth
+		path := ctx.Request.URL.Path
+		if auth == nil || path == faker.Prefix+tokenPath ||
+			strings.HasPrefix(path, faker.Prefix+adminPath+"/") || strings.HasPrefix(path, faker.Prefix+"/.well-known/") {
+			return
+		}
+
+		identity, hasCredentials := auth.authenticate(ctx.Request)
+		if identity != nil {
+			ctx.Set(IdentityKey, identity)
+		}
+
+		resource := faker.resourceOfRoute(ctx)
+		if auth.isPublic(ctx.Request.Method, resource) {
+			return
+		}
+
+		if identity != nil {
+			if scopes := auth.requiredScopes(ctx.Request.Method, resource); !identity.hasScopes(scopes) {
+				ctx.Header("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope="%s"`, strings.Join(scopes, " ")))
+				ctx.JSON(http.StatusForbidden, map[string]string{"message": "insufficient scope"})
+				ctx.Abort()
+			}
+			return
+		}
+
+		if auth.Bearer {
+			ctx.Header("WWW-Authenticate", `Bearer realm="apifaker"`)
+		} else if auth.Basic {
+			ctx.Header("WWW-Authenticate", `Basic realm="apifaker"`)
+		}
+
+		if hasCredentials {
+			ctx.JSON(auth.InvalidStatus, map[string]string{"message": "invalid credentials"})
+		} else {
+			ctx.JSON(http.StatusUnauthorized, map[string]string{"message": "authentication required"})
+		}
+		ctx.Abort()
+	}
+}
+
+// resourceOfRoute returns the resource to match rules for the request, it is the last resource in the matched route,
+// like "books" of "/users/:id/books", or the first segment if the route has no resource, like "health" of "/health"
+func (af *ApiFaker) resourceOfRoute(ctx *gin.Context) string {
+	path := ctx.FullPath()
+	if path == "" {
+		path = ctx.Request.URL.Path
+	}
+
+	pieces := strings.Split(strings.Trim(strings.TrimPrefix(path, af.Prefix), "/"), "/")
+	for i := len(pieces) - 1; i >= 0; i-- {
+		if _, ok := af.Routers[pieces[i]]; ok {
+			return pieces[i]
+		}
+	}
+	return pieces[0]
+}
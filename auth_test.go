@@ -0,0 +1,181 @@
+package apifaker
+
+import (
+	"encoding/json"
+	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newAuthTestDir creates a temp dir with resources accounts and posts, returns the dir
+func newAuthTestDir() string {
+	dir, _ := ioutil.TempDir("", "apifaker")
+	ioutil.WriteFile(filepath.Join(dir, "accounts.json"), []byte(`{"resource_name": "accounts",
+		"columns": [{"name": "id", "type": "number"}, {"name": "name", "type": "string", "unique": true},
+			{"name": "password", "type": "string"}, {"name": "role", "type": "string"}],
+		"seeds": [{"id": 1, "name": "frank", "password": "secret", "role": "admin"},
+			{"id": 2, "name": "tony", "password": "jarvis", "role": "member"}]}`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "posts.json"), []byte(`{"resource_name": "posts",
+		"columns": [{"name": "id", "type": "number"}, {"name": "title", "type": "string"}, {"name": "account_id", "type": "number"}],
+		"seeds": [{"id": 1, "title": "a", "account_id": 1}, {"id": 2, "title": "b", "account_id": 2}]}`), 0644)
+	return dir
+}
+
+func TestAuth(t *testing.T) {
+	dir := newAuthTestDir()
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "routes.json"), []byte(`{"routes": [{"method": "GET", "path": "/accounts/:id/posts", "body": []}]}`), 0644)
+
+	faker, _ := NewWithApiDir(dir)
+	faker.InMemory = true
+	request := func(method, path string, form url.Values, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		for key := range header {
+			req.Header.Set(key, header.Get(key))
+		}
+		rw := httptest.NewRecorder()
+		faker.ServeHTTP(rw, req)
+		return rw
+	}
+
+	err := faker.SetAuth(&Auth{
+		ApiKeys:      map[string]*Identity{"key-1": {Subject: "ci", Role: "admin"}},
+		Basic:        true,
+		Bearer:       true,
+		UserResource: "accounts",
+		RoleColumn:   "role",
+		Public:       []string{"GET posts"},
+	})
+
+	Describ("SetAuth", t, func() {
+		It("checks the Auth", func() {
+			Expect(err, ShouldBeNil)
+			Expect(faker.SetAuth(&Auth{Basic: true, UserResource: "foos"}), ShouldNotBeNil)
+			Expect(faker.SetAuth(&Auth{Public: []string{"GET posts x"}}), ShouldNotBeNil)
+		})
+
+		Context("when the token endpoint conflicts with a custom route", func() {
+			dir := newAuthTestDir()
+			defer os.RemoveAll(dir)
+			ioutil.WriteFile(filepath.Join(dir, "routes.json"), []byte(`{"routes": [{"method": "POST", "path": "/auth/token"}]}`), 0644)
+			faker, _ := NewWithApiDir(dir)
+
+			It("returns error and keeps the Auth", func() {
+				Expect(faker.SetAuth(&Auth{Bearer: true, UserResource: "accounts"}), ShouldNotBeNil)
+				Expect(faker.Auth, ShouldBeNil)
+			})
+		})
+	})
+
+	Describ("AuthMiddleware", t, func() {
+		Context("when a protected api has no credentials", func() {
+			It("responses 401", func() {
+				response := request("GET", "/accounts/1", nil, nil)
+				Expect(response.Code, ShouldEqual, http.StatusUnauthorized)
+				Expect(response.Header().Get("WWW-Authenticate"), ShouldContainSubstring, "Bearer")
+			})
+		})
+
+		Context("when the api is public", func() {
+			It("responses without credentials", func() {
+				Expect(request("GET", "/posts", nil, nil).Code, ShouldEqual, http.StatusOK)
+				Expect(request("POST", "/posts", url.Values{"title": {"c"}, "account_id": {"1"}}, nil).Code, ShouldEqual, http.StatusUnauthorized)
+			})
+
+			It("uses the rules of the last resource of the matched route", func() {
+				Expect(request("GET", "/accounts/1/posts", nil, nil).Code, ShouldEqual, http.StatusOK)
+			})
+		})
+
+		Context("when uses an api key", func() {
+			It("authenticates the api key", func() {
+				Expect(request("GET", "/accounts/1", nil, http.Header{"X-Api-Key": {"key-1"}}).Code, ShouldEqual, http.StatusOK)
+				Expect(request("GET", "/accounts/1", nil, http.Header{"X-Api-Key": {"key-2"}}).Code, ShouldEqual, http.StatusUnauthorized)
+			})
+		})
+
+		Context("when uses basic auth", func() {
+			It("authenticates the user", func() {
+				req := httptest.NewRequest("GET", "/accounts/1", nil)
+				req.SetBasicAuth("frank", "secret")
+				rw := httptest.NewRecorder()
+				faker.ServeHTTP(rw, req)
+				Expect(rw.Code, ShouldEqual, http.StatusOK)
+
+				req.SetBasicAuth("frank", "wrong")
+				rw = httptest.NewRecorder()
+				faker.ServeHTTP(rw, req)
+				Expect(rw.Code, ShouldEqual, http.StatusUnauthorized)
+			})
+		})
+
+		Context("when uses bearer tokens", func() {
+			It("issues and refreshes tokens", func() {
+				response := request("POST", "/auth/token", url.Values{"username": {"tony"}, "password": {"jarvis"}}, nil)
+				Expect(response.Code, ShouldEqual, http.StatusOK)
+				tokens := map[string]interface{}{}
+				json.Unmarshal(response.Body.Bytes(), &tokens)
+				Expect(tokens["token_type"], ShouldEqual, "Bearer")
+
+				bearer := http.Header{"Authorization": {"Bearer " + tokens["access_token"].(string)}}
+				Expect(request("GET", "/accounts/2", nil, bearer).Code, ShouldEqual, http.StatusOK)
+				Expect(request("GET", "/accounts/2", nil, http.Header{"Authorization": {"Bearer wrong"}}).Code, ShouldEqual, http.StatusUnauthorized)
+
+				refresh := url.Values{"grant_type": {"refresh_token"}, "refresh_token": {tokens["refresh_token"].(string)}}
+				Expect(request("POST", "/auth/token", refresh, nil).Code, ShouldEqual, http.StatusOK)
+				Expect(request("POST", "/auth/token", refresh, nil).Code, ShouldEqual, http.StatusBadRequest)
+			})
+
+			It("removes expired tokens when issuing", func() {
+				expired := &issuedToken{identity: &Identity{Subject: "1"}, expiresAt: time.Now().Add(-time.Second)}
+				faker.Auth.tokens["expired"] = expired
+				faker.Auth.refreshTokens["expired"] = expired
+				request("POST", "/auth/token", url.Values{"username": {"tony"}, "password": {"jarvis"}}, nil)
+				Expect(faker.Auth.tokens["expired"], ShouldBeNil)
+				Expect(faker.Auth.refreshTokens["expired"], ShouldBeNil)
+			})
+
+			It("rejects wrong password", func() {
+				response := request("POST", "/auth/token", url.Values{"username": {"tony"}, "password": {"x"}}, nil)
+				Expect(response.Code, ShouldEqual, http.StatusBadRequest)
+			})
+
+			It("accepts json bodies with numbers and booleans", func() {
+				faker.Routers["accounts"].Model.Add(NewLineItemWithMap(map[string]interface{}{"name": "bruce", "password": "1234", "role": "member"}))
+				jsonRequest := func(body string) *httptest.ResponseRecorder {
+					req := httptest.NewRequest("POST", "/auth/token", strings.NewReader(body))
+					req.Header.Set("Content-Type", "application/json")
+					rw := httptest.NewRecorder()
+					faker.ServeHTTP(rw, req)
+					return rw
+				}
+
+				Expect(jsonRequest(`{"username": "bruce", "password": 1234, "remember": true}`).Code, ShouldEqual, http.StatusOK)
+				response := jsonRequest(`{"username": "bruce",`)
+				Expect(response.Code, ShouldEqual, http.StatusBadRequest)
+				Expect(response.Body.String(), ShouldContainSubstring, "invalid_request")
+			})
+		})
+	})
+
+	Describ("InvalidStatus", t, func() {
+		faker, _ := NewWithApiDir(dir)
+		faker.SetAuth(&Auth{ApiKeys: map[string]*Identity{"key-1": {Subject: "ci"}}, InvalidStatus: http.StatusForbidden})
+
+		It("is used for invalid credentials", func() {
+			req := httptest.NewRequest("GET", "/posts", nil)
+			req.Header.Set("X-Api-Key", "key-2")
+			rw := httptest.NewRecorder()
+			faker.ServeHTTP(rw, req)
+			Expect(rw.Code, ShouldEqual, http.StatusForbidden)
+		})
+	})
+}
@@ -0,0 +1,104 @@
+package apifaker
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newBenchFaker allocates and returns a new ApiFaker contains the given count of users,
+// every user has two books and one avatar
+func newBenchFaker(count int) *ApiFaker {
+	gin.DefaultWriter = ioutil.Discard
+	faker := &ApiFaker{Routers: map[string]*Router{}}
+
+	newRouter := func(name string, columns []*Column) *Router {
+		router := &Router{apiFaker: faker}
+		router.Model = NewModel(router)
+		router.Model.Name = name
+		router.Model.Columns = columns
+		router.setRestRoutes()
+		faker.Routers[name] = router
+		return router
+	}
+
+	users := newRouter("users", []*Column{
+		{Name: "id", Type: "number"},
+		{Name: "name", Type: "string", Unique: true},
+	})
+	users.Model.HasMany = []string{"books"}
+	users.Model.HasOne = []string{"avatar"}
+	books := newRouter("books", []*Column{
+		{Name: "id", Type: "number"},
+		{Name: "title", Type: "string", Unique: true},
+		{Name: "user_id", Type: "number"},
+	})
+	avatars := newRouter("avatars", []*Column{
+		{Name: "id", Type: "number"},
+		{Name: "url", Type: "string"},
+		{Name: "user_id", Type: "number"},
+	})
+
+	for i := 1; i <= count; i++ {
+		id := float64(i)
+		users.Model.Seeds = append(users.Model.Seeds,
+			map[string]interface{}{"id": id, "name": fmt.Sprintf("user%d", i)})
+		books.Model.Seeds = append(books.Model.Seeds,
+			map[string]interface{}{"id": id*2 - 1, "title": fmt.Sprintf("book%d", i*2-1), "user_id": id},
+			map[string]interface{}{"id": id * 2, "title": fmt.Sprintf("book%d", i*2), "user_id": id})
+		avatars.Model.Seeds = append(avatars.Model.Seeds,
+			map[string]interface{}{"id": id, "url": fmt.Sprintf("http://example.com/%d.png", i), "user_id": id})
+	}
+
+	for _, router := range faker.Routers {
+		router.Model.initSet()
+	}
+	faker.setHandlers()
+	return faker
+}
+
+func benchmarkRequest(b *testing.B, faker *ApiFaker, method, path string) {
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		req, _ := http.NewRequest(method, path, nil)
+		rw := httptest.NewRecorder()
+		faker.ServeHTTP(rw, req)
+		if rw.Code != http.StatusOK {
+			b.Fatalf("%s %s responses %d", method, path, rw.Code)
+		}
+	}
+}
+
+func BenchmarkGetCollection1000(b *testing.B) {
+	benchmarkRequest(b, newBenchFaker(1000), "GET", "/users")
+}
+
+func BenchmarkGetCollection5000(b *testing.B) {
+	benchmarkRequest(b, newBenchFaker(5000), "GET", "/users")
+}
+
+func BenchmarkGetItem5000(b *testing.B) {
+	benchmarkRequest(b, newBenchFaker(5000), "GET", "/users/2500")
+}
+
+func BenchmarkFilterByForeignKey5000(b *testing.B) {
+	benchmarkRequest(b, newBenchFaker(5000), "GET", "/books?user_id=2500")
+}
+
+func BenchmarkFilterByUniqueColumn5000(b *testing.B) {
+	benchmarkRequest(b, newBenchFaker(5000), "GET", "/users?name=user2500")
+}
+
+func BenchmarkCheckRelationships5000(b *testing.B) {
+	faker := newBenchFaker(5000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := faker.CheckRelationships(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
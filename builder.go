@@ -0,0 +1,351 @@
+package apifaker
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/Focinfi/gtester"
+)
+
+// Columns the columns of a resource, the first one must be id with number type
+type Columns []*Column
+
+// Builder defines resources in Go instead of json files, like
+//
+//	apifaker.New().
+//	    Resource("users", apifaker.Columns{{Name: "id", Type: "number"}, {Name: "name", Type: "string"}}).HasMany("books").
+//	    ResourceOf("books", Book{}).Seeds(Book{Id: 1, Title: "Emma", UserId: 1}).
+//	    Build()
+//
+// HasMany, HasOne, Seeds and Configure apply to the last added resource
+type Builder struct {
+	models []*Model
+	err    error
+}
+
+// New allocates and returns a new Builder without resources
+func New() *Builder {
+	return &Builder{}
+}
+
+// Resource adds a resource with the given name and columns
+func (b *Builder) Resource(name string, columns Columns) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if name == "" {
+		b.err = BuilderErrorf("resource name must be present")
+		return b
+	}
+
+	model := NewModel(nil)
+	model.Name = name
+	model.Columns = columns
+	b.models = append(b.models, model)
+	return b
+}
+
+// ResourceOf adds a resource with the given name and the columns of the given struct, see ColumnsOf
+func (b *Builder) ResourceOf(name string, v interface{}) *Builder {
+	if b.err != nil {
+		return b
+	}
+
+	columns, err := ColumnsOf(v)
+	if err != nil {
+		b.err = err
+		return b
+	}
+	return b.Resource(name, columns)
+}
+
+// HasMany adds the names of resources into HasMany of the last resource
+func (b *Builder) HasMany(names ...string) *Builder {
+	return b.Configure(func(model *Model) {
+		model.HasMany = append(model.HasMany, names...)
+	})
+}
+
+// HasOne adds the names of resources into HasOne of the last resource
+func (b *Builder) HasOne(names ...string) *Builder {
+	return b.Configure(func(model *Model) {
+		model.HasOne = append(model.HasOne, names...)
+	})
+}
+
+// Seeds adds seeds into the last resource, every seed is a map, a struct or a slice of them,
+// the keys of structs are the names of columns derived by ColumnsOf,
+// an absent id or a zero id is generated by Build after the largest id of the resource
+func (b *Builder) Seeds(seeds ...interface{}) *Builder {
+	if b.err != nil {
+		return b
+	}
+
+	values := []map[string]interface{}{}
+	for _, seed := range seeds {
+		rows, err := seedsOf(reflect.ValueOf(seed))
+		if err != nil {
+			b.err = err
+			return b
+		}
+		values = append(values, rows...)
+	}
+
+	return b.Configure(func(model *Model) {
+		model.Seeds = append(model.Seeds, values...)
+	})
+}
+
+// Configure calls the given func with the last resource to set the other fields, like SoftDelete or Latency
+func (b *Builder) Configure(configure func(model *Model)) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if len(b.models) == 0 {
+		b.err = BuilderErrorf("no resource to configure, call Resource or ResourceOf first")
+		return b
+	}
+
+	configure(b.models[len(b.models)-1])
+	return b
+}
+
+// Build checks all resources like NewWithApiDir and returns an ApiFaker serves them,
+// the ApiFaker is InMemory because it has no files, the Builder should not be used after Build
+func (b *Builder) Build() (*ApiFaker, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	faker := &ApiFaker{
+		Routers:  map[string]*Router{},
+		InMemory: true,
+		closed:   make(chan struct{}),
+	}
+
+	err := gtester.NewInspector().Check(func() error {
+		for _, model := range b.models {
+			if _, ok := faker.Routers[model.Name]; ok {
+				return JsonFileErrorf("%s has been existed", model.Name)
+			}
+
+			router := &Router{Model: model, apiFaker: faker}
+			model.router = router
+			fillSeedIds(model.Seeds)
+			if err := model.setUp(""); err != nil {
+				return err
+			}
+			router.setRestRoutes()
+			faker.Routers[model.Name] = router
+		}
+		return nil
+	}).
+		Check(faker.CheckUniqueness).
+		Check(faker.CheckRelationships).
+		Check(faker.CheckRoutes).
+		Then(func() {
+			faker.setHandlers()
+		})
+
+	return faker, err
+}
+
+// ColumnsOf derives the columns from the exported fields of the given struct or its pointer:
+//  1. the name is the name of the json tag, or the snake_case field name
+//  2. the type is derived from the field type, time.Time is datetime, maps and structs are object
+//  3. the apifaker tag sets the other fields, like `apifaker:"unique,auto=create,type=string,pattern=^132"`,
+//     pattern must be the last one, it may contain commas
+//
+// the fields tagged with "-" are skipped, the fields of embedded structs are promoted, id is moved to the first
+func ColumnsOf(v interface{}) (Columns, error) {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, BuilderErrorf("can not derive columns from %T, must be a struct", v)
+	}
+
+	columns := Columns{}
+	err := eachColumnField(t, nil, func(field reflect.StructField, name string, _ []int) error {
+		column, err := columnOf(field, name)
+		if err != nil {
+			return err
+		}
+
+		if name == "id" {
+			columns = append(Columns{column}, columns...)
+		} else {
+			columns = append(columns, column)
+		}
+		return nil
+	})
+	return columns, err
+}
+
+// columnOf returns the Column of the field with the given name
+func columnOf(field reflect.StructField, name string) (*Column, error) {
+	column := &Column{Name: name, Type: jsonTypeOfGo(field.Type).Name()}
+
+	tag := field.Tag.Get("apifaker")
+	for tag != "" {
+		option := tag
+		if strings.HasPrefix(tag, "pattern=") {
+			tag = ""
+		} else if i := strings.Index(tag, ","); i >= 0 {
+			option, tag = tag[:i], tag[i+1:]
+		} else {
+			tag = ""
+		}
+
+		key, value := option, ""
+		if i := strings.Index(option, "="); i >= 0 {
+			key, value = option[:i], option[i+1:]
+		}
+		switch key {
+		case "":
+		case "unique":
+			column.Unique = true
+		case "pattern":
+			column.RegexpPattern = value
+		case "auto":
+			column.Auto = value
+		case "type":
+			column.Type = value
+		default:
+			return nil, BuilderErrorf("field %s has unknown option in apifaker tag: %s", field.Name, option)
+		}
+	}
+
+	if column.Type == "" {
+		return nil, BuilderErrorf("can not derive the type of field %s from %s, set it by the apifaker tag", field.Name, field.Type)
+	}
+	return column, nil
+}
+
+// timeType the type of time.Time
+var timeType = reflect.TypeOf(time.Time{})
+
+// jsonTypeOfGo returns the JsonType of the given Go type, empty if it has no JsonType
+func jsonTypeOfGo(t reflect.Type) JsonType {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return datetime
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return boolean
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return number
+	case reflect.String:
+		return str
+	case reflect.Slice, reflect.Array:
+		return array
+	case reflect.Map, reflect.Struct:
+		return object
+	}
+	return ""
+}
+
+// eachColumnField calls the given func with every field of the struct type which is a column,
+// its column name and its index for reflect.Value.FieldByIndex
+func eachColumnField(t reflect.Type, index []int, f func(field reflect.StructField, name string, index []int) error) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fieldIndex := append(append([]int{}, index...), i)
+		if field.Tag.Get("apifaker") == "-" {
+			continue
+		}
+
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct && field.Type != timeType {
+			if err := eachColumnField(field.Type, fieldIndex, f); err != nil {
+				return err
+			}
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+
+		if name == "" {
+			name = toSnakeCase(field.Name)
+		}
+		if err := f(field, name, fieldIndex); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fillSeedIds sets the ids of the seeds without id or with a zero id, they follow the largest id of the seeds
+func fillSeedIds(seeds []map[string]interface{}) {
+	for _, seed := range seeds {
+		if id, ok := seed["id"].(float64); ok && id == 0 {
+			delete(seed, "id")
+		}
+	}
+
+	maxId := float64(0)
+	for _, seed := range seeds {
+		if id, ok := seed["id"].(float64); ok && id > maxId {
+			maxId = id
+		}
+	}
+
+	for _, seed := range seeds {
+		if _, ok := seed["id"]; !ok {
+			maxId++
+			seed["id"] = maxId
+		}
+	}
+}
+
+// seedsOf returns the seeds of the given map, struct or slice of them, values are converted by json
+func seedsOf(v reflect.Value) ([]map[string]interface{}, error) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		seeds := []map[string]interface{}{}
+		for i := 0; i < v.Len(); i++ {
+			elemSeeds, err := seedsOf(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			seeds = append(seeds, elemSeeds...)
+		}
+		return seeds, nil
+	case reflect.Struct:
+		values := map[string]interface{}{}
+		eachColumnField(v.Type(), nil, func(field reflect.StructField, name string, index []int) error {
+			value := v.FieldByIndex(index)
+			if value.IsZero() && (name == "id" || strings.Contains(field.Tag.Get("apifaker"), "auto=")) {
+				// filled by fillSeedIds or fillSeedsAutoValues like an absent value
+				return nil
+			}
+			values[name] = value.Interface()
+			return nil
+		})
+		return seedsOf(reflect.ValueOf(values))
+	case reflect.Map:
+		seed := map[string]interface{}{}
+		if err := decodeByJSON(v.Interface(), &seed); err != nil {
+			return nil, SeedsErrorf("%v", err)
+		}
+		return []map[string]interface{}{seed}, nil
+	}
+	return nil, SeedsErrorf("seed must be a map, a struct or a slice of them, but use a %s", v.Kind())
+}
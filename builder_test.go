@@ -0,0 +1,123 @@
+package apifaker
+
+import (
+	"encoding/json"
+	. "github.com/smartystreets/goconvey/convey"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type testTimestamps struct {
+	CreatedAt time.Time `apifaker:"auto=create"`
+}
+
+type testMember struct {
+	ID    int
+	Name  string `apifaker:"unique"`
+	Phone string `apifaker:"pattern=^1[0-9]{2,3}$"`
+	Tags  []string
+	Note  string `apifaker:"-"`
+	testTimestamps
+}
+
+type testNote struct {
+	Id    int    `json:"id"`
+	Title string `json:"title"`
+}
+
+func TestBuilder(t *testing.T) {
+	Describ("ColumnsOf", t, func() {
+		It("derives the columns from the struct", func() {
+			columns, err := ColumnsOf(&testMember{})
+			Expect(err, ShouldBeNil)
+			Expect(columns, ShouldResemble, Columns{
+				{Name: "id", Type: "number"},
+				{Name: "name", Type: "string", Unique: true},
+				{Name: "phone", Type: "string", RegexpPattern: "^1[0-9]{2,3}$"},
+				{Name: "tags", Type: "array"},
+				{Name: "created_at", Type: "datetime", Auto: "create"},
+			})
+
+			columns, err = ColumnsOf(testBook{})
+			Expect(err, ShouldBeNil)
+			Expect(columns[2].Name, ShouldEqual, "user_id")
+		})
+
+		It("returns errors of non-structs and unknown tag options", func() {
+			_, err := ColumnsOf(1)
+			Expect(err, ShouldNotBeNil)
+			_, err = ColumnsOf(struct {
+				Id int `apifaker:"foo"`
+			}{})
+			Expect(err, ShouldNotBeNil)
+			_, err = ColumnsOf(struct{ Id interface{} }{})
+			Expect(err, ShouldNotBeNil)
+		})
+	})
+
+	Describ("Build", t, func() {
+		faker, err := New().
+			Resource("users", Columns{{Name: "id", Type: "number"}, {Name: "name", Type: "string", Unique: true}}).
+			HasMany("books").
+			Seeds(map[string]interface{}{"id": 1, "name": "Frank"}).
+			ResourceOf("books", testBook{}).
+			Seeds([]testBook{{Id: 1, Title: "Emma", UserId: 1}, {Id: 2, Title: "Dune", UserId: 1}}).
+			ResourceOf("members", testMember{}).
+			Seeds(testMember{ID: 1, Name: "Ameng", Phone: "132", Tags: []string{}}).
+			Build()
+
+		It("serves the resources", func() {
+			Expect(err, ShouldBeNil)
+
+			rw := httptest.NewRecorder()
+			faker.ServeHTTP(rw, httptest.NewRequest("GET", "/users/1", nil))
+			Expect(rw.Code, ShouldEqual, http.StatusOK)
+			user := map[string]interface{}{}
+			json.Unmarshal(rw.Body.Bytes(), &user)
+			Expect(len(user["books"].([]interface{})), ShouldEqual, 2)
+
+			row, err := faker.Resource("members").Find(1)
+			Expect(err, ShouldBeNil)
+			Expect(row["created_at"], ShouldNotBeEmpty)
+			_, err = faker.Resource("members").Create(testMember{Name: "Ameng", Phone: "133"})
+			Expect(err, ShouldNotBeNil)
+		})
+
+		It("generates the absent and zero ids", func() {
+			faker, err := New().ResourceOf("notes", testNote{}).
+				Seeds(testNote{Title: "a"}, map[string]interface{}{"id": 5, "title": "b"}, testNote{Title: "c"}, map[string]interface{}{"id": 0, "title": "d"}).
+				Build()
+			Expect(err, ShouldBeNil)
+			row, _ := faker.Resource("notes").Find(6)
+			Expect(row["title"], ShouldEqual, "a")
+			row, _ = faker.Resource("notes").Find(7)
+			Expect(row["title"], ShouldEqual, "c")
+			row, _ = faker.Resource("notes").Find(8)
+			Expect(row["title"], ShouldEqual, "d")
+		})
+
+		It("builds a unique column without seeds", func() {
+			_, err := New().Resource("tags", Columns{{Name: "id", Type: "number"}, {Name: "name", Type: "string", Unique: true}}).Build()
+			Expect(err, ShouldBeNil)
+		})
+
+		It("checks the resources like json files", func() {
+			_, err := New().Resource("users", Columns{{Name: "name", Type: "string"}}).Build()
+			Expect(err, ShouldNotBeNil)
+
+			_, err = New().ResourceOf("users", testMember{}).HasMany("books").Seeds(testMember{ID: 1, Name: "A", Phone: "132"}).Build()
+			Expect(err, ShouldNotBeNil)
+
+			_, err = New().ResourceOf("books", testBook{}).Seeds(testBook{Id: 1, Title: "Emma", UserId: 1}).Build()
+			Expect(err, ShouldNotBeNil)
+
+			_, err = New().ResourceOf("users", testMember{}).ResourceOf("users", testMember{}).Build()
+			Expect(err, ShouldNotBeNil)
+
+			_, err = New().HasMany("books").Build()
+			Expect(err, ShouldNotBeNil)
+		})
+	})
+}
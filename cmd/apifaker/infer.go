@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/Focinfi/apifaker"
+)
+
+// infer prints or writes the resource files inferred from the given json payload files,
+// reads the payload from stdin if no file is given
+func infer(args []string) int {
+	flags := flag.NewFlagSet("apifaker infer", flag.ContinueOnError)
+	name := flags.String("name", "", "the resource name, like \"users\"(required)")
+	out := flags.String("out", "", "the directory to write resource files into, prints to stdout if empty")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *name == "" {
+		fmt.Fprintln(os.Stderr, "-name is required")
+		flags.Usage()
+		return 2
+	}
+
+	payloads := [][]byte{}
+	if flags.NArg() == 0 {
+		payload, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		payloads = append(payloads, payload)
+	}
+	for _, path := range flags.Args() {
+		payload, err := ioutil.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		payloads = append(payloads, payload)
+	}
+
+	models, err := apifaker.Infer(*name, payloads...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	for _, model := range models {
+		bytes, err := json.MarshalIndent(model, "", "    ")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+
+		if *out == "" {
+			fmt.Println(string(bytes))
+			continue
+		}
+
+		path := filepath.Join(*out, model.Name+".json")
+		if err := ioutil.WriteFile(path, append(bytes, '\n'), 0644); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "wrote %s\n", path)
+	}
+	return 0
+}
@@ -0,0 +1,198 @@
+// Command apifaker starts a fake json api server with the api json files in a directory.
+//
+// Usage:
+//
+//	apifaker [flags]
+//	apifaker validate [-json] [dir...]
+//	apifaker infer -name <resource_name> [-out dir] [payload.json...]
+//
+// Flags:
+//
+//	-dir        the directory contains api json files (default ".")
+//	-addr       the address to listen on (default ":3000")
+//	-prefix     the prefix of fake apis, like "/fake_api"
+//	-persist    "file" saves changes back to files, "memory" keeps changes in memory only (default "file")
+//	-cors       allows cross-origin requests from any origin
+//	-cors-origins  the comma separated origins allowed to send cross-origin requests, like "http://localhost:*", implies -cors
+//	-latency    the default latency of all resources, like "200ms"
+//	-jitter     the uniform jitter of the default latency, like "50ms"
+//	-read-only  rejects POST, PUT, PATCH and DELETE requests
+//	-record     forwards requests to the upstream url and records the responses
+//	-cassette   the cassette file to record into, resource files in dir are written if it is empty
+//	-replay     replays the recorded cassette file offline
+//	-fallthrough  forwards the requests under prefix which no fake api serves to the upstream url
+//	-auth       the json file of the auth config, like api keys, basic auth and bearer tokens
+//
+// It shuts down gracefully on SIGINT or SIGTERM, and saves the changes if persist is "file",
+// the resource files are left untouched with -read-only or if no data changed.
+//
+// The validate command reports every problem of the api json files in the given directories,
+// it exits with 1 if any problem is found, so it can be used as a pre-commit check.
+//
+// The infer command infers resource files from json payloads, like a real api response,
+// the payloads are read from stdin if no file is given.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Focinfi/apifaker"
+)
+
+const (
+	persistFile   = "file"
+	persistMemory = "memory"
+)
+
+func main() {
+	args := os.Args[1:]
+	if len(args) > 0 {
+		switch args[0] {
+		case "validate":
+			os.Exit(validate(args[1:]))
+		case "infer":
+			os.Exit(infer(args[1:]))
+		}
+	}
+	os.Exit(serve(args))
+}
+
+// serve parses the flags and serves the fake apis until a signal received,
+// returns the exit code
+func serve(args []string) int {
+	flags := flag.NewFlagSet("apifaker", flag.ContinueOnError)
+	dir := flags.String("dir", ".", "the directory contains api json files")
+	addr := flags.String("addr", ":3000", "the address to listen on")
+	prefix := flags.String("prefix", "", "the prefix of fake apis, like \"/fake_api\"")
+	persist := flags.String("persist", persistFile, "\"file\" saves changes back to files, \"memory\" keeps changes in memory only")
+	cors := flags.Bool("cors", false, "allows cross-origin requests from any origin")
+	corsOrigins := flags.String("cors-origins", "", "the comma separated origins allowed to send cross-origin requests, like \"http://localhost:*\", implies -cors")
+	latency := flags.Duration("latency", 0, "the default latency of all resources, like \"200ms\"")
+	jitter := flags.Duration("jitter", 0, "the uniform jitter of the default latency, like \"50ms\"")
+	readOnly := flags.Bool("read-only", false, "rejects POST, PUT, PATCH and DELETE requests")
+	record := flags.String("record", "", "forwards requests to the upstream url and records the responses")
+	cassette := flags.String("cassette", "", "the cassette file to record into, resource files in dir are written if it is empty")
+	replay := flags.String("replay", "", "replays the recorded cassette file offline")
+	authFile := flags.String("auth", "", "the json file of the auth config, like api keys, basic auth and bearer tokens")
+	fallthroughURL := flags.String("fallthrough", "", "forwards the requests under prefix which no fake api serves to the upstream url")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *persist != persistFile && *persist != persistMemory {
+		fmt.Fprintf(os.Stderr, "unknown persist mode: %s, must be %s or %s\n", *persist, persistFile, persistMemory)
+		return 2
+	}
+	if *record != "" && *replay != "" {
+		fmt.Fprintln(os.Stderr, "record and replay can not be used together")
+		return 2
+	}
+
+	newFaker := apifaker.NewWithApiDir
+	if *persist == persistMemory {
+		newFaker = apifaker.NewWithApiDirInMemory
+	}
+	faker, err := newFaker(*dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer faker.Close()
+
+	faker.ReadOnly = *readOnly
+	if *prefix != "" {
+		faker.MountTo(*prefix)
+	}
+	if *latency > 0 || *jitter > 0 {
+		err := faker.SetLatency("", &apifaker.Latency{
+			Fixed:  float64(*latency) / float64(time.Millisecond),
+			Jitter: float64(*jitter) / float64(time.Millisecond),
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	}
+
+	if *authFile != "" {
+		auth, err := apifaker.NewAuthWithPath(*authFile)
+		if err == nil {
+			err = faker.SetAuth(auth)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	}
+	if *fallthroughURL != "" {
+		if err := faker.Fallthrough(*fallthroughURL); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	}
+	if *record != "" {
+		if err := faker.Record(*record, *cassette); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	} else if *replay != "" {
+		if err := faker.Replay(*replay); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+
+	if *cors || *corsOrigins != "" {
+		config := &apifaker.CORS{AllowCredentials: true}
+		if *corsOrigins != "" {
+			config.AllowOrigins = strings.Split(*corsOrigins, ",")
+		}
+		if err := faker.SetCORS(config); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	}
+
+	server := &http.Server{Addr: *addr, Handler: faker}
+	serveErr := make(chan error, 1)
+	go func() {
+		log.Printf("apifaker serves %s on %s", *dir, *addr)
+		serveErr <- server.ListenAndServe()
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	select {
+	case err := <-serveErr:
+		log.Println(err)
+		return 1
+	case sig := <-signals:
+		log.Printf("received %v, shutting down", sig)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Println(err)
+	}
+
+	// saving rewrites the resource files, keep them untouched if nothing changed
+	if *readOnly || !faker.DataChanged() {
+		return 0
+	}
+	if err := faker.SaveToFile(); err != nil {
+		log.Println(err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var It = Convey
+var Expect = So
+
+var testDir = filepath.Join("..", "..", "api_static_test")
+
+// captureStdout runs f and returns its exit code and what it printed to stdout
+func captureStdout(f func() int) (int, string) {
+	stdout := os.Stdout
+	reader, writer, _ := os.Pipe()
+	os.Stdout = writer
+	code := f()
+	writer.Close()
+	os.Stdout = stdout
+
+	bytes, _ := ioutil.ReadAll(reader)
+	reader.Close()
+	return code, string(bytes)
+}
+
+func TestServe(t *testing.T) {
+	It("exits with 2 if the flags are invalid", t, func() {
+		Expect(serve([]string{"-unknown"}), ShouldEqual, 2)
+		Expect(serve([]string{"-latency", "soon"}), ShouldEqual, 2)
+		Expect(serve([]string{"-dir", testDir, "-persist", "disk"}), ShouldEqual, 2)
+		Expect(serve([]string{"-dir", testDir, "-record", "http://localhost", "-replay", "cassette.json"}), ShouldEqual, 2)
+	})
+
+	It("exits with 1 if the dir can not be loaded", t, func() {
+		Expect(serve([]string{"-dir", filepath.Join(testDir, "nonexistent"), "-persist", "memory"}), ShouldEqual, 1)
+	})
+}
+
+func TestValidate(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "apifaker")
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "users.json"), []byte(`{"resource_name": "users", "columns": [{"name": "id", "type": "number"}],
+		"seeds": [{"id": "one"}]}`), 0644)
+
+	It("exits with 0 if no problem is found", t, func() {
+		code, _ := captureStdout(func() int { return validate([]string{testDir}) })
+		Expect(code, ShouldEqual, 0)
+	})
+
+	It("exits with 1 and prints the problems if any problem is found", t, func() {
+		code, output := captureStdout(func() int { return validate([]string{"-json", dir}) })
+		problems := []map[string]interface{}{}
+		Expect(code, ShouldEqual, 1)
+		Expect(json.Unmarshal([]byte(output), &problems), ShouldBeNil)
+		Expect(len(problems), ShouldBeGreaterThan, 0)
+	})
+
+	It("exits with 2 if the flags or the dirs are invalid", t, func() {
+		Expect(validate([]string{"-unknown"}), ShouldEqual, 2)
+		Expect(validate([]string{filepath.Join(dir, "nonexistent")}), ShouldEqual, 2)
+	})
+}
+
+func TestInfer(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "apifaker")
+	defer os.RemoveAll(dir)
+	payload := filepath.Join(dir, "users.payload.json")
+	ioutil.WriteFile(payload, []byte(`[{"id": 1, "name": "Frank", "age": 18}]`), 0644)
+
+	It("prints the inferred resource files", t, func() {
+		code, output := captureStdout(func() int { return infer([]string{"-name", "users", payload}) })
+		model := map[string]interface{}{}
+		Expect(code, ShouldEqual, 0)
+		Expect(json.Unmarshal([]byte(output), &model), ShouldBeNil)
+		Expect(model["resource_name"], ShouldEqual, "users")
+		Expect(len(model["columns"].([]interface{})), ShouldEqual, 3)
+		Expect(len(model["seeds"].([]interface{})), ShouldEqual, 1)
+	})
+
+	It("writes the inferred resource files into the out dir", t, func() {
+		code, output := captureStdout(func() int { return infer([]string{"-name", "users", "-out", dir, payload}) })
+		_, err := os.Stat(filepath.Join(dir, "users.json"))
+		Expect(code, ShouldEqual, 0)
+		Expect(output, ShouldEqual, "")
+		Expect(err, ShouldBeNil)
+	})
+
+	It("exits with 2 if the name is missing", t, func() {
+		Expect(infer([]string{payload}), ShouldEqual, 2)
+	})
+
+	It("exits with 1 if a payload can not be read", t, func() {
+		Expect(infer([]string{"-name", "users", filepath.Join(dir, "nonexistent.json")}), ShouldEqual, 1)
+	})
+}
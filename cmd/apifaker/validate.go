@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Focinfi/apifaker"
+)
+
+// validate prints all problems of the api json files in the given dirs,
+// returns 1 if any problem is found
+func validate(args []string) int {
+	flags := flag.NewFlagSet("apifaker validate", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "prints problems as a json array")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	dirs := flags.Args()
+	if len(dirs) == 0 {
+		dirs = []string{"."}
+	}
+
+	problems := []apifaker.Problem{}
+	for _, dir := range dirs {
+		if _, err := os.Stat(dir); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		problems = append(problems, apifaker.Validate(dir)...)
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(problems)
+	} else {
+		for _, problem := range problems {
+			fmt.Println(problem)
+		}
+	}
+
+	if len(problems) > 0 {
+		if !*asJSON {
+			fmt.Fprintf(os.Stderr, "%d problems found\n", len(problems))
+		}
+		return 1
+	}
+	return 0
+}
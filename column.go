@@ -3,10 +3,10 @@ package apifaker
 import (
 	"fmt"
 	. "github.com/Focinfi/gset"
-	"github.com/jinzhu/inflection"
 	"reflect"
 	"regexp"
 	"strings"
+	"time"
 )
 
 type JsonType string
@@ -17,6 +17,9 @@ const (
 	str     JsonType = "string"
 	array   JsonType = "array"
 	object  JsonType = "object"
+
+	// datetime a string in RFC 3339 format, like "2006-01-02T15:04:05Z"
+	datetime JsonType = "datetime"
 )
 
 // Name returns JsonType string itself
@@ -31,7 +34,7 @@ func (j JsonType) GoType() string {
 		return "bool"
 	case number:
 		return "float64"
-	case str:
+	case str, datetime:
 		return "string"
 	case array:
 		return "[]interface {}"
@@ -42,14 +45,18 @@ func (j JsonType) GoType() string {
 }
 
 // jsonTypes contains a list a supportted json types
-var jsonTypes = NewSetSimple(boolean, number, str, array, object)
+var jsonTypes = NewSetSimple(boolean, number, str, array, object, datetime)
 
 type Column struct {
 	Name          string `json:"name"`
 	Type          string `json:"type"`
 	Unique        bool   `json:"unique"`
 	RegexpPattern string `json:"regexp_pattern"`
-	uniqueValues  *SetThreadSafe
+
+	// Auto manages the value automatically, "create" or "update", the type must be datetime
+	Auto string `json:"auto,omitempty"`
+
+	uniqueValues *SetThreadSafe
 }
 
 func (column *Column) getUniqueValues() *SetThreadSafe {
@@ -63,6 +70,7 @@ func (column *Column) getUniqueValues() *SetThreadSafe {
 //   1. Name and Type must be present
 //   2. Type must in jsonTypes
 //   3. RegexpPattern must valid
+//   4. Auto must be "create" or "update" with datetime type if it is present
 func (column Column) CheckMeta() error {
 	if column.Name == "" {
 		return ColumnsErrorf("colmun[content=%v] must has a name", column)
@@ -83,6 +91,16 @@ func (column Column) CheckMeta() error {
 		}
 	}
 
+	switch column.Auto {
+	case "":
+	case autoCreate, autoUpdate:
+		if column.Type != datetime.Name() {
+			return ColumnsErrorf("%s is auto, its type must be %s", columnLogName, datetime)
+		}
+	default:
+		return ColumnsErrorf("%s use unknown auto: %s, must be %s or %s", columnLogName, column.Auto, autoCreate, autoUpdate)
+	}
+
 	return nil
 }
 
@@ -94,15 +112,10 @@ func (column *Column) CheckRelationships(seedVal interface{}, model *Model) erro
 
 	columnLogName := fmt.Sprintf("column[name=\"%s\"]", column.Name)
 	resName := strings.TrimSuffix(column.Name, "_id")
-	resPluralName := inflection.Plural(resName)
+	resPluralName := plural(resName)
 	router, ok := model.router.apiFaker.Routers[resPluralName]
-	if ok {
-		for _, li := range router.Model.ToLineItems() {
-			id, ok := li.Get("id")
-			if ok && id == seedVal {
-				return nil
-			}
-		}
+	if id, isNumber := seedVal.(float64); ok && isNumber && router.Model.Has(id) {
+		return nil
 	}
 
 	return ColumnsErrorf("%s has no item[id=%v] of resource[resource_name=\"%s\"]", columnLogName, seedVal, resPluralName)
@@ -110,17 +123,27 @@ func (column *Column) CheckRelationships(seedVal interface{}, model *Model) erro
 
 // CheckValue checks the value to insert database
 //   1. type
-//   2. regexp pattern matching
-//   3. uniqueness if unique is true
+//   2. RFC 3339 format if type is datetime
+//   3. regexp pattern matching
+//   4. uniqueness if unique is true
 func (column *Column) CheckValue(seedVal interface{}, model *Model) error {
 	columnLogName := fmt.Sprintf("column[name=\"%s\"]", column.Name)
 	goType := JsonType(column.Type).GoType()
 	jsonType := JsonType(column.Type).Name()
+	if seedVal == nil {
+		return ColumnsErrorf("%s must not be null", columnLogName)
+	}
 	seedType := reflect.TypeOf(seedVal).String()
 	if seedType != goType {
 		return ColumnsErrorf("%s has wrong type, expect a %s, but use a %s", columnLogName, jsonType, seedType)
 	}
 
+	if column.Type == datetime.Name() {
+		if _, err := time.Parse(time.RFC3339, seedVal.(string)); err != nil {
+			return ColumnsErrorf("%s must be in RFC 3339 format, value: %v", columnLogName, seedVal)
+		}
+	}
+
 	if column.RegexpPattern != "" && column.Type == str.Name() {
 		matched, err := regexp.Match(column.RegexpPattern, []byte(seedVal.(string)))
 		if err == nil && !matched {
@@ -0,0 +1,175 @@
+package apifaker
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CORS allows cross-origin requests of browsers
+type CORS struct {
+	// AllowOrigins the allowed origins, "*" matches any characters, like "https://*.example.com"
+	// or "http://localhost:*", default ["*"]
+	AllowOrigins []string `json:"allow_origins,omitempty"`
+
+	// AllowMethods the allowed methods, default the methods of the routes of the request path
+	AllowMethods []string `json:"allow_methods,omitempty"`
+
+	// AllowHeaders the allowed request headers, default the headers asked by the preflight request
+	AllowHeaders []string `json:"allow_headers,omitempty"`
+
+	// ExposeHeaders the response headers can be read by browsers, like ["ETag"]
+	ExposeHeaders []string `json:"expose_headers,omitempty"`
+
+	// AllowCredentials allows cookies and Authorization headers
+	AllowCredentials bool `json:"allow_credentials,omitempty"`
+
+	// MaxAge the seconds the preflight response can be cached
+	MaxAge int `json:"max_age,omitempty"`
+
+	originPatterns []*regexp.Regexp
+}
+
+// SetCORS checks and sets the CORS, nil disallows cross-origin requests
+func (af *ApiFaker) SetCORS(cors *CORS) error {
+	if cors != nil {
+		if err := cors.CheckMeta(); err != nil {
+			return err
+		}
+	}
+	af.CORS = cors
+	return nil
+}
+
+// CheckMeta sets the default AllowOrigins and checks
+//  1. every method of AllowMethods must be supported, they are upper cased
+//  2. MaxAge must not be negative
+func (cors *CORS) CheckMeta() error {
+	if len(cors.AllowOrigins) == 0 {
+		cors.AllowOrigins = []string{"*"}
+	}
+
+	cors.originPatterns = []*regexp.Regexp{}
+	for _, origin := range cors.AllowOrigins {
+		pattern := strings.Replace(regexp.QuoteMeta(strings.TrimSpace(origin)), `\*`, ".*", -1)
+		cors.originPatterns = append(cors.originPatterns, regexp.MustCompile("^"+pattern+"$"))
+	}
+
+	for i, name := range cors.AllowMethods {
+		method, err := ParseRestMethod(name)
+		if err != nil {
+			return CORSErrorf("%v", err)
+		}
+		cors.AllowMethods[i] = method.String()
+	}
+
+	if cors.MaxAge < 0 {
+		return CORSErrorf("max_age must not be negative: %d", cors.MaxAge)
+	}
+	return nil
+}
+
+// allowsOrigin returns if the origin matches one of AllowOrigins
+func (cors *CORS) allowsOrigin(origin string) bool {
+	for _, pattern := range cors.originPatterns {
+		if pattern.MatchString(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// handle sets the CORS headers of the cross-origin request,
+// returns true if it is a preflight request and has been responded:
+//  1. 204 with the allowed methods of the routes of the path
+//  2. 403 if the origin is not allowed
+//
+// preflight requests of the paths without routes are not handled
+func (cors *CORS) handle(rw http.ResponseWriter, req *http.Request, af *ApiFaker) bool {
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		return false
+	}
+
+	header := rw.Header()
+	header.Add("Vary", "Origin")
+	preflight := req.Method == http.MethodOptions && req.Header.Get("Access-Control-Request-Method") != ""
+	if !cors.allowsOrigin(origin) {
+		if preflight {
+			rw.WriteHeader(http.StatusForbidden)
+		}
+		return preflight
+	}
+
+	if cors.AllowCredentials || !containsString(cors.AllowOrigins, "*") {
+		header.Set("Access-Control-Allow-Origin", origin)
+	} else {
+		header.Set("Access-Control-Allow-Origin", "*")
+	}
+	if cors.AllowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if !preflight {
+		if len(cors.ExposeHeaders) > 0 {
+			header.Set("Access-Control-Expose-Headers", strings.Join(cors.ExposeHeaders, ", "))
+		}
+		return false
+	}
+
+	methods := []string{}
+	for _, method := range af.methodsOfPath(req.URL.Path) {
+		if len(cors.AllowMethods) == 0 || containsString(cors.AllowMethods, method) {
+			methods = append(methods, method)
+		}
+	}
+	if len(methods) == 0 {
+		return false
+	}
+
+	header.Add("Vary", "Access-Control-Request-Method")
+	header.Add("Vary", "Access-Control-Request-Headers")
+	header.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+	if len(cors.AllowHeaders) > 0 {
+		header.Set("Access-Control-Allow-Headers", strings.Join(cors.AllowHeaders, ", "))
+	} else if headers := req.Header.Get("Access-Control-Request-Headers"); headers != "" {
+		header.Set("Access-Control-Allow-Headers", headers)
+	}
+	if cors.MaxAge > 0 {
+		header.Set("Access-Control-Max-Age", strconv.Itoa(cors.MaxAge))
+	}
+	rw.WriteHeader(http.StatusNoContent)
+	return true
+}
+
+// methodsOfPath returns the sorted methods of the routes which match the request path
+func (af *ApiFaker) methodsOfPath(path string) []string {
+	methods := []string{}
+	for _, route := range af.Routes() {
+		if matchRoutePath(route.Path, path) {
+			methods = appendUnique(methods, route.Method)
+		}
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// matchRoutePath returns if the request path matches the route path,
+// ":param" matches a segment and "*param" matches the rest
+func matchRoutePath(routePath, path string) bool {
+	routeSegments := strings.Split(strings.Trim(routePath, "/"), "/")
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, routeSegment := range routeSegments {
+		if strings.HasPrefix(routeSegment, "*") {
+			return true
+		}
+		if i >= len(segments) {
+			return false
+		}
+		if routeSegment != segments[i] && !(strings.HasPrefix(routeSegment, ":") && segments[i] != "") {
+			return false
+		}
+	}
+	return len(routeSegments) == len(segments)
+}
@@ -0,0 +1,74 @@
+package apifaker
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCORS(t *testing.T) {
+	faker, _ := NewWithApiDir(testDir)
+	faker.InMemory = true
+	request := func(method, path, origin, requestMethod string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Origin", origin)
+		if requestMethod != "" {
+			req.Header.Set("Access-Control-Request-Method", requestMethod)
+			req.Header.Set("Access-Control-Request-Headers", "Content-Type")
+		}
+		rw := httptest.NewRecorder()
+		faker.ServeHTTP(rw, req)
+		return rw
+	}
+
+	err := faker.SetCORS(&CORS{
+		AllowOrigins:  []string{"http://localhost:*", "https://*.example.com"},
+		ExposeHeaders: []string{"ETag"},
+		MaxAge:        600,
+	})
+
+	Describ("SetCORS", t, func() {
+		It("checks the CORS", func() {
+			Expect(err, ShouldBeNil)
+			Expect(faker.SetCORS(&CORS{AllowMethods: []string{"FETCH"}}), ShouldNotBeNil)
+			Expect(faker.SetCORS(&CORS{MaxAge: -1}), ShouldNotBeNil)
+		})
+
+		It("upper cases AllowMethods", func() {
+			cors := &CORS{AllowMethods: []string{"get", "Post"}}
+			Expect(cors.CheckMeta(), ShouldBeNil)
+			Expect(cors.AllowMethods, ShouldResemble, []string{"GET", "POST"})
+		})
+	})
+
+	Describ("preflight requests", t, func() {
+		It("responses the methods of the routes", func() {
+			response := request("OPTIONS", "/users", "http://localhost:8080", "POST")
+			Expect(response.Code, ShouldEqual, http.StatusNoContent)
+			Expect(response.Header().Get("Access-Control-Allow-Origin"), ShouldEqual, "http://localhost:8080")
+			Expect(response.Header().Get("Access-Control-Allow-Methods"), ShouldEqual, "GET, POST")
+			Expect(response.Header().Get("Access-Control-Allow-Headers"), ShouldEqual, "Content-Type")
+			Expect(response.Header().Get("Access-Control-Max-Age"), ShouldEqual, "600")
+
+			response = request("OPTIONS", "/users/1", "https://app.example.com", "DELETE")
+			Expect(response.Header().Get("Access-Control-Allow-Methods"), ShouldEqual, "DELETE, GET, PATCH, PUT")
+		})
+
+		It("rejects the origins not allowed", func() {
+			Expect(request("OPTIONS", "/users", "http://evil.com", "GET").Code, ShouldEqual, http.StatusForbidden)
+		})
+	})
+
+	Describ("cross-origin requests", t, func() {
+		It("sets the CORS headers", func() {
+			response := request("GET", "/users/1", "http://localhost:8080", "")
+			Expect(response.Code, ShouldEqual, http.StatusOK)
+			Expect(response.Header().Get("Access-Control-Allow-Origin"), ShouldEqual, "http://localhost:8080")
+			Expect(response.Header().Get("Access-Control-Expose-Headers"), ShouldEqual, "ETag")
+
+			response = request("GET", "/users/1", "http://evil.com", "")
+			Expect(response.Header().Get("Access-Control-Allow-Origin"), ShouldEqual, "")
+		})
+	})
+}
@@ -24,6 +24,46 @@ func SeedsErrorf(format string, a ...interface{}) error {
 	return fmt.Errorf("Error [apifaker-seeds]: "+format, a...)
 }
 
+func LatencyErrorf(format string, a ...interface{}) error {
+	return fmt.Errorf("Error [apifaker-latency]: "+format, a...)
+}
+
+func FaultErrorf(format string, a ...interface{}) error {
+	return fmt.Errorf("Error [apifaker-faults]: "+format, a...)
+}
+
+func ProxyErrorf(format string, a ...interface{}) error {
+	return fmt.Errorf("Error [apifaker-proxy]: "+format, a...)
+}
+
+func RoutesErrorf(format string, a ...interface{}) error {
+	return fmt.Errorf("Error [apifaker-routes]: "+format, a...)
+}
+
+func TransformErrorf(format string, a ...interface{}) error {
+	return fmt.Errorf("Error [apifaker-transform]: "+format, a...)
+}
+
+func AuthErrorf(format string, a ...interface{}) error {
+	return fmt.Errorf("Error [apifaker-auth]: "+format, a...)
+}
+
+func PermissionsErrorf(format string, a ...interface{}) error {
+	return fmt.Errorf("Error [apifaker-permissions]: "+format, a...)
+}
+
+func CORSErrorf(format string, a ...interface{}) error {
+	return fmt.Errorf("Error [apifaker-cors]: "+format, a...)
+}
+
+func RateLimitErrorf(format string, a ...interface{}) error {
+	return fmt.Errorf("Error [apifaker-rate-limits]: "+format, a...)
+}
+
+func BuilderErrorf(format string, a ...interface{}) error {
+	return fmt.Errorf("Error [apifaker-builder]: "+format, a...)
+}
+
 func ResponseErrorMsg(err error) map[string]string {
 	return map[string]string{"message": err.Error()}
 }
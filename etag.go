@@ -0,0 +1,104 @@
+package apifaker
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errPreconditionFailed is returned by the updates whose If-Match header does not match the ETag of the item
+var errPreconditionFailed = errors.New("the item has been changed")
+
+// etagOf returns the quoted hash of the json of the response item,
+// it changes whenever the item, its related data or the Transform of its Model changes
+func etagOf(item interface{}) string {
+	bytes, _ := json.Marshal(item)
+	sum := sha1.Sum(bytes)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+// setETag sets the ETag header of the response with the ETag of the response item
+func setETag(ctx *gin.Context, item interface{}) {
+	ctx.Header("ETag", etagOf(item))
+}
+
+// representation returns the response of GET /collection/:id for the LineItem
+func (model *Model) representation(ctx *gin.Context, li LineItem) interface{} {
+	return model.render(model.transform(model.readable(ctx, li.InsertRelatedData(model))))
+}
+
+// matchETag returns if the ETag matches the value of If-Match or If-None-Match header,
+// the value is a list of ETags separated by comma or "*",
+// weak ETags only match with the weak comparison of If-None-Match
+func matchETag(header, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// ifMatch returns the precondition of the If-Match header of the request, nil if the header is absent,
+// the updates check it under the write lock of Model so that no other update happens between the check and the update
+func (model *Model) ifMatch(ctx *gin.Context) func(li LineItem) error {
+	header := ctx.Request.Header.Get("If-Match")
+	if header == "" {
+		return nil
+	}
+
+	return func(li LineItem) error {
+		if !matchETag(header, etagOf(model.representation(ctx, li)), false) {
+			return errPreconditionFailed
+		}
+		return nil
+	}
+}
+
+// statusOfUpdateError returns 412 if the update failed because of its If-Match header, otherwise 400
+func statusOfUpdateError(err error) int {
+	if err == errPreconditionFailed {
+		return http.StatusPreconditionFailed
+	}
+	return http.StatusBadRequest
+}
+
+// ConditionalMiddleware returns a gin.HandlerFunc handles conditional requests of /collection/:id,
+// it must be used after the id check middleware:
+//  1. GET responses 304 if If-None-Match matches the ETag of the item
+//  2. PUT, PATCH and DELETE response 412 if If-Match does not match the ETag of the item,
+//     their handlers check it while updating the item, see Model.ifMatch
+func ConditionalMiddleware(faker *ApiFaker) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id, ok := ctx.Get("idFloat64")
+		if !ok || ctx.Request.Method != http.MethodGet {
+			return
+		}
+
+		router, ok := faker.routerOfPath(ctx.Request.URL.Path)
+		if !ok || ctx.Request.URL.Path != faker.Prefix+"/"+router.Model.Name+"/"+ctx.Param("id") {
+			return
+		}
+		header := ctx.Request.Header.Get("If-None-Match")
+		li, ok := router.Model.Get(id.(float64))
+		if header == "" || !ok {
+			return
+		}
+
+		etag := etagOf(router.Model.representation(ctx, li))
+		if matchETag(header, etag, true) {
+			ctx.Header("ETag", etag)
+			ctx.Status(http.StatusNotModified)
+			ctx.Abort()
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package apifaker
+
+import (
+	"fmt"
+	. "github.com/smartystreets/goconvey/convey"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestETag(t *testing.T) {
+	faker, _ := NewWithApiDir(testDir)
+	faker.InMemory = true
+	request := func(method, path string, form url.Values, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		for key := range header {
+			req.Header.Set(key, header.Get(key))
+		}
+		rw := httptest.NewRecorder()
+		faker.ServeHTTP(rw, req)
+		return rw
+	}
+
+	Describ("ETag", t, func() {
+		etag := request("GET", "/users/3", nil, nil).Header().Get("ETag")
+
+		It("is returned on GET", func() {
+			Expect(etag, ShouldStartWith, `"`)
+			Expect(request("GET", "/users/3", nil, nil).Header().Get("ETag"), ShouldEqual, etag)
+		})
+
+		Context("when If-None-Match matches", func() {
+			It("responses 304", func() {
+				response := request("GET", "/users/3", nil, http.Header{"If-None-Match": {`"x", ` + etag}})
+				Expect(response.Code, ShouldEqual, http.StatusNotModified)
+				Expect(response.Body.String(), ShouldBeEmpty)
+			})
+		})
+
+		Context("when If-Match does not match", func() {
+			It("responses 412 on updates and deletes", func() {
+				header := http.Header{"If-Match": {`"x"`}}
+				Expect(request("PATCH", "/users/3", url.Values{"age": {"23"}}, header).Code, ShouldEqual, http.StatusPreconditionFailed)
+				Expect(request("DELETE", "/users/3", nil, header).Code, ShouldEqual, http.StatusPreconditionFailed)
+				Expect(faker.Routers["users"].Model.Has(3), ShouldBeTrue)
+			})
+		})
+
+		Context("when If-Match matches", func() {
+			It("updates the item and returns the new ETag", func() {
+				response := request("PATCH", "/users/3", url.Values{"age": {"23"}}, http.Header{"If-Match": {etag}})
+				Expect(response.Code, ShouldEqual, http.StatusOK)
+
+				newETag := response.Header().Get("ETag")
+				Expect(newETag, ShouldNotEqual, etag)
+				Expect(request("GET", "/users/3", nil, http.Header{"If-None-Match": {etag}}).Code, ShouldEqual, http.StatusOK)
+				Expect(request("PATCH", "/users/3", url.Values{"age": {"24"}}, http.Header{"If-Match": {etag}}).Code, ShouldEqual, http.StatusPreconditionFailed)
+			})
+		})
+
+		Context("when If-Match is a weak ETag", func() {
+			etag := request("GET", "/users/3", nil, nil).Header().Get("ETag")
+			It("responses 412 on updates but 304 on GET", func() {
+				header := http.Header{"If-Match": {"W/" + etag}}
+				Expect(request("PATCH", "/users/3", url.Values{"age": {"25"}}, header).Code, ShouldEqual, http.StatusPreconditionFailed)
+				Expect(request("GET", "/users/3", nil, http.Header{"If-None-Match": {"W/" + etag}}).Code, ShouldEqual, http.StatusNotModified)
+			})
+		})
+
+		Context("when concurrent updates have the same If-Match", func() {
+			etag := request("GET", "/users/2", nil, nil).Header().Get("ETag")
+			codes := make(chan int, 10)
+			wg := sync.WaitGroup{}
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func(age int) {
+					defer wg.Done()
+					codes <- request("PATCH", "/users/2", url.Values{"age": {fmt.Sprint(age)}}, http.Header{"If-Match": {etag}}).Code
+				}(30 + i)
+			}
+			wg.Wait()
+			close(codes)
+
+			It("updates the item only once", func() {
+				succeeded := 0
+				for code := range codes {
+					if code == http.StatusOK {
+						succeeded++
+					} else {
+						Expect(code, ShouldEqual, http.StatusPreconditionFailed)
+					}
+				}
+				Expect(succeeded, ShouldEqual, 1)
+			})
+		})
+
+		Context("when the related data changes", func() {
+			etag := request("GET", "/users/1", nil, nil).Header().Get("ETag")
+			request("PATCH", "/books/1", url.Values{"title": {"Dune"}}, nil)
+			It("changes the ETag", func() {
+				Expect(request("GET", "/users/1", nil, http.Header{"If-None-Match": {etag}}).Code, ShouldEqual, http.StatusOK)
+				Expect(request("GET", "/users/1", nil, nil).Header().Get("ETag"), ShouldNotEqual, etag)
+			})
+		})
+	})
+}
@@ -0,0 +1,241 @@
+package apifaker
+
+import (
+	"encoding/json"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// ForceStatusHeader the request header forces a fault with the given status code
+	ForceStatusHeader = "X-Apifaker-Force-Status"
+
+	// adminPath the path of admin apis
+	adminPath = "/_apifaker"
+)
+
+// Fault describes an error injected into the responses of a resource
+type Fault struct {
+	// Methods the methods the Fault applies to, empty means all methods
+	Methods []string `json:"methods,omitempty"`
+
+	// Probability the chance in (0, 1] to inject the Fault
+	Probability float64 `json:"probability"`
+
+	// Status the status code of the response
+	Status int `json:"status,omitempty"`
+
+	// Headers the extra headers of the response, like "Retry-After"
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// Body the json body of the response, default {"message": "<status text>"}
+	Body interface{} `json:"body,omitempty"`
+
+	// Drop resets the connection without any response
+	Drop bool `json:"drop,omitempty"`
+
+	// Timeout holds the request until the client gives up
+	Timeout bool `json:"timeout,omitempty"`
+}
+
+// CheckMeta checks
+//  1. Probability must be in (0, 1]
+//  2. every element of Methods must be a RestMethod
+//  3. Status must be a valid status code if neither Drop nor Timeout is true
+func (fault *Fault) CheckMeta() error {
+	if fault.Probability <= 0 || fault.Probability > 1 {
+		return FaultErrorf("probability must be in (0, 1]: %v", fault.Probability)
+	}
+
+	for _, name := range fault.Methods {
+		if _, err := ParseRestMethod(name); err != nil {
+			return FaultErrorf("%v", err)
+		}
+	}
+
+	if !fault.Drop && !fault.Timeout && (fault.Status < 100 || fault.Status > 999) {
+		return FaultErrorf("status must be a valid status code: %d", fault.Status)
+	}
+
+	return nil
+}
+
+// appliesTo returns if the Fault applies to the given method
+func (fault *Fault) appliesTo(method string) bool {
+	if len(fault.Methods) == 0 {
+		return true
+	}
+	for _, name := range fault.Methods {
+		if strings.EqualFold(name, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// inject writes the Fault into the response and aborts the request
+func (fault *Fault) inject(ctx *gin.Context) {
+	ctx.Abort()
+
+	if fault.Drop {
+		dropConnection(ctx)
+		return
+	}
+
+	if fault.Timeout {
+		<-ctx.Request.Context().Done()
+		return
+	}
+
+	for key, value := range fault.Headers {
+		ctx.Header(key, value)
+	}
+	if fault.Body != nil {
+		ctx.JSON(fault.Status, fault.Body)
+	} else {
+		ctx.JSON(fault.Status, map[string]string{"message": http.StatusText(fault.Status)})
+	}
+}
+
+// dropConnection closes the connection of the request with a TCP reset
+func dropConnection(ctx *gin.Context) {
+	hijacker, ok := ctx.Writer.(http.Hijacker)
+	if !ok {
+		return
+	}
+
+	conn, _, err := hijacker.Hijack()
+	if err != nil {
+		return
+	}
+	if tcpConn, ok := conn.(*net.TCPConn); ok {
+		tcpConn.SetLinger(0)
+	}
+	conn.Close()
+}
+
+// SetFaults sets the Faults of the resource with the given name, nil removes all Faults,
+// they are never saved into the json file of the resource
+func (af *ApiFaker) SetFaults(name string, faults []*Fault) error {
+	for _, fault := range faults {
+		if err := fault.CheckMeta(); err != nil {
+			return err
+		}
+	}
+
+	router, ok := af.Routers[name]
+	if !ok {
+		return JsonFileErrorf("unknown resource: %s", name)
+	}
+
+	af.faultsLock.Lock()
+	defer af.faultsLock.Unlock()
+	router.Model.runtimeFaults = faults
+	router.Model.runtimeFaultsSet = true
+	return nil
+}
+
+// currentFaults returns the Faults set by ApiFaker.SetFaults if they have been set, otherwise the loaded Faults
+func (model *Model) currentFaults() []*Fault {
+	if model.runtimeFaultsSet {
+		return model.runtimeFaults
+	}
+	return model.Faults
+}
+
+// Faults returns the Faults of all resources, using resource name as the key
+func (af *ApiFaker) Faults() map[string][]*Fault {
+	af.faultsLock.RLock()
+	defer af.faultsLock.RUnlock()
+
+	faults := map[string][]*Fault{}
+	for name, router := range af.Routers {
+		if modelFaults := router.Model.currentFaults(); len(modelFaults) > 0 {
+			faults[name] = modelFaults
+		}
+	}
+	return faults
+}
+
+// faultOf returns the Fault should be injected into the given request,
+// the ForceStatusHeader uses the Fault with the same status or a default one, or a 400 one if it is not in [100, 599]
+func (af *ApiFaker) faultOf(ctx *gin.Context) (*Fault, bool) {
+	router, ok := af.routerOfPath(ctx.Request.URL.Path)
+	if !ok {
+		return nil, false
+	}
+
+	af.faultsLock.RLock()
+	defer af.faultsLock.RUnlock()
+
+	method := ctx.Request.Method
+	if forceStatus := ctx.Request.Header.Get(ForceStatusHeader); forceStatus != "" {
+		status, err := strconv.Atoi(forceStatus)
+		if err != nil || status < 100 || status > 599 {
+			err := FaultErrorf("%s must be a status code in [100, 599]: %s", ForceStatusHeader, forceStatus)
+			return &Fault{Probability: 1, Status: http.StatusBadRequest, Body: ResponseErrorMsg(err)}, true
+		}
+		for _, fault := range router.Model.currentFaults() {
+			if fault.Status == status && !fault.Drop && !fault.Timeout && fault.appliesTo(method) {
+				return fault, true
+			}
+		}
+		return &Fault{Probability: 1, Status: status}, true
+	}
+
+	for _, fault := range router.Model.currentFaults() {
+		if fault.appliesTo(method) && rand.Float64() < fault.Probability {
+			return fault, true
+		}
+	}
+	return nil, false
+}
+
+// FaultMiddleware returns a gin.HandlerFunc which injects Faults into responses
+func FaultMiddleware(faker *ApiFaker) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if fault, ok := faker.faultOf(ctx); ok {
+			fault.inject(ctx)
+		}
+	}
+}
+
+// setFaultHandlers sets the admin apis of Faults:
+//
+//	GET    /_apifaker/faults
+//	PUT    /_apifaker/faults/:resource
+//	DELETE /_apifaker/faults/:resource
+func (af *ApiFaker) setFaultHandlers() {
+	path := af.Prefix + adminPath + "/faults"
+
+	af.GET(path, func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, af.Faults())
+	})
+
+	af.PUT(path+"/:resource", func(ctx *gin.Context) {
+		faults := []*Fault{}
+		err := json.NewDecoder(ctx.Request.Body).Decode(&faults)
+		if err == nil {
+			err = af.SetFaults(ctx.Param("resource"), faults)
+		}
+
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, ResponseErrorMsg(err))
+		} else {
+			ctx.JSON(http.StatusOK, faults)
+		}
+	})
+
+	af.DELETE(path+"/:resource", func(ctx *gin.Context) {
+		if err := af.SetFaults(ctx.Param("resource"), nil); err != nil {
+			ctx.JSON(http.StatusNotFound, ResponseErrorMsg(err))
+		} else {
+			ctx.JSON(http.StatusOK, nil)
+		}
+	})
+}
@@ -0,0 +1,146 @@
+package apifaker
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFaults(t *testing.T) {
+	faker, _ := NewWithApiDir(testDir)
+	request := func(method, path, body string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		for key := range header {
+			req.Header.Set(key, header.Get(key))
+		}
+		rw := httptest.NewRecorder()
+		faker.ServeHTTP(rw, req)
+		return rw
+	}
+
+	Describ("CheckMeta", t, func() {
+		Context("when probability is out of range", func() {
+			It("returns error", func() {
+				Expect((&Fault{Probability: 1.5, Status: 500}).CheckMeta(), ShouldNotBeNil)
+				Expect((&Fault{Status: 500}).CheckMeta(), ShouldNotBeNil)
+			})
+		})
+		Context("when status is missing", func() {
+			It("returns error", func() {
+				Expect((&Fault{Probability: 1}).CheckMeta(), ShouldNotBeNil)
+				Expect((&Fault{Probability: 1, Drop: true}).CheckMeta(), ShouldBeNil)
+			})
+		})
+	})
+
+	Describ("FaultMiddleware", t, func() {
+		faker.SetFaults("users", []*Fault{{
+			Methods:     []string{"GET"},
+			Probability: 1,
+			Status:      http.StatusServiceUnavailable,
+			Headers:     map[string]string{"Retry-After": "5"},
+		}})
+
+		Context("when a fault matches the request", func() {
+			response := request("GET", "/users/1", "", nil)
+			It("responses the fault", func() {
+				Expect(response.Code, ShouldEqual, http.StatusServiceUnavailable)
+				Expect(response.Header().Get("Retry-After"), ShouldEqual, "5")
+			})
+		})
+
+		Context("when the methods of the fault are lower case", func() {
+			faker.SetFaults("books", []*Fault{{Methods: []string{"get"}, Probability: 1, Status: http.StatusBadGateway}})
+			defer faker.SetFaults("books", nil)
+			response := request("GET", "/books/1", "", nil)
+			It("responses the fault", func() {
+				Expect(response.Code, ShouldEqual, http.StatusBadGateway)
+			})
+		})
+
+		Context("when no fault matches the request", func() {
+			response := request("GET", "/books/1", "", nil)
+			It("responses normally", func() {
+				Expect(response.Code, ShouldEqual, http.StatusOK)
+			})
+		})
+
+		Context("when pass the force status header", func() {
+			response := request("GET", "/books/1", "", http.Header{ForceStatusHeader: {"500"}})
+			It("responses the forced status", func() {
+				Expect(response.Code, ShouldEqual, http.StatusInternalServerError)
+			})
+
+			response = request("GET", "/avatars/1", "", http.Header{ForceStatusHeader: {"503"}})
+			It("uses the fault with the same status of the resource", func() {
+				Expect(response.Code, ShouldEqual, http.StatusServiceUnavailable)
+				Expect(response.Header().Get("Retry-After"), ShouldEqual, "")
+			})
+
+			for _, status := range []string{"42", "-1", "600", "x"} {
+				response = request("GET", "/books/1", "", http.Header{ForceStatusHeader: {status}})
+				It("responses 400 for the invalid status "+status, func() {
+					Expect(response.Code, ShouldEqual, http.StatusBadRequest)
+				})
+			}
+		})
+
+		Context("when drop the connection", func() {
+			faker.SetFaults("books", []*Fault{{Probability: 1, Drop: true}})
+			server := httptest.NewServer(faker)
+			defer server.Close()
+			_, err := http.Get(server.URL + "/books/1")
+			It("resets the connection", func() {
+				Expect(err, ShouldNotBeNil)
+			})
+		})
+	})
+
+	Describ("Admin apis", t, func() {
+		Context("PUT /_apifaker/faults/:resource", func() {
+			response := request("PUT", "/_apifaker/faults/books", `[{"probability": 1, "status": 500}]`, nil)
+			It("sets the faults of the resource", func() {
+				Expect(response.Code, ShouldEqual, http.StatusOK)
+				Expect(request("GET", "/books", "", nil).Code, ShouldEqual, http.StatusInternalServerError)
+				Expect(len(faker.Faults()["books"]), ShouldEqual, 1)
+			})
+
+			response = request("PUT", "/_apifaker/faults/books", `[{"probability": 2, "status": 500}]`, nil)
+			It("returns 400 if a fault is invalid", func() {
+				Expect(response.Code, ShouldEqual, http.StatusBadRequest)
+			})
+		})
+
+		Context("DELETE /_apifaker/faults/:resource", func() {
+			response := request("DELETE", "/_apifaker/faults/books", "", nil)
+			It("removes the faults of the resource", func() {
+				Expect(response.Code, ShouldEqual, http.StatusOK)
+				Expect(request("GET", "/books", "", nil).Code, ShouldEqual, http.StatusOK)
+			})
+		})
+	})
+
+	Describ("SaveToFile", t, func() {
+		dir, _ := ioutil.TempDir("", "apifaker")
+		defer os.RemoveAll(dir)
+		ioutil.WriteFile(filepath.Join(dir, "posts.json"), []byte(`{"resource_name": "posts",
+			"columns": [{"name": "id", "type": "number"}, {"name": "title", "type": "string"}],
+			"seeds": [{"id": 1, "title": "a"}]}`), 0644)
+		faker, _ := NewWithApiDir(dir)
+		faker.SetFaults("posts", []*Fault{{Probability: 1, Status: http.StatusInternalServerError}})
+		faker.SetLatency("posts", &Latency{Fixed: 30})
+		faker.SaveToFile()
+
+		reloaded, _ := NewWithApiDir(dir)
+		It("never saves the faults and the latency set at runtime", func() {
+			Expect(len(reloaded.Routers["posts"].Model.Faults), ShouldEqual, 0)
+			Expect(reloaded.Routers["posts"].Model.Latency, ShouldBeNil)
+			Expect(len(reloaded.Faults()["posts"]), ShouldEqual, 0)
+		})
+	})
+}
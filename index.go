@@ -0,0 +1,205 @@
+package apifaker
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Index maps values of a Column to ids of the LineItems which have the value
+type Index struct {
+	ids map[interface{}]map[float64]bool
+	sync.RWMutex
+}
+
+// NewIndex allocates and returns a new Index
+func NewIndex() *Index {
+	return &Index{ids: map[interface{}]map[float64]bool{}}
+}
+
+// Add adds the id into the ids of the value
+func (index *Index) Add(value interface{}, id float64) {
+	if !isIndexable(value) {
+		return
+	}
+
+	index.Lock()
+	defer index.Unlock()
+	ids, ok := index.ids[value]
+	if !ok {
+		ids = map[float64]bool{}
+		index.ids[value] = ids
+	}
+	ids[id] = true
+}
+
+// Remove removes the id from the ids of the value
+func (index *Index) Remove(value interface{}, id float64) {
+	if !isIndexable(value) {
+		return
+	}
+
+	index.Lock()
+	defer index.Unlock()
+	if ids, ok := index.ids[value]; ok {
+		delete(ids, id)
+		if len(ids) == 0 {
+			delete(index.ids, value)
+		}
+	}
+}
+
+// Ids returns the sorted ids of the value
+func (index *Index) Ids(value interface{}) []float64 {
+	index.RLock()
+	defer index.RUnlock()
+
+	ids := []float64{}
+	if !isIndexable(value) {
+		return ids
+	}
+	for id := range index.ids[value] {
+		ids = append(ids, id)
+	}
+	sort.Float64s(ids)
+	return ids
+}
+
+// isIndexable returns if the value can be a key of Index
+func isIndexable(value interface{}) bool {
+	switch value.(type) {
+	case string, float64, bool:
+		return true
+	}
+	return false
+}
+
+// isIndexed returns if the Column should has an Index,
+// foreign key columns and unique columns have indexes
+func (column *Column) isIndexed() bool {
+	if column.Name == "id" {
+		return false
+	}
+	return column.Unique || strings.HasSuffix(column.Name, "_id")
+}
+
+// index returns the Index of the Column with the given name,
+// returns nil if the Column has no Index
+func (model *Model) index(name string) *Index {
+	model.indexesLock.Lock()
+	defer model.indexesLock.Unlock()
+
+	if index, ok := model.indexes[name]; ok {
+		return index
+	}
+
+	for _, column := range model.Columns {
+		if column.Name == name && column.isIndexed() {
+			if model.indexes == nil {
+				model.indexes = map[string]*Index{}
+			}
+			index := NewIndex()
+			model.indexes[name] = index
+			return index
+		}
+	}
+	return nil
+}
+
+// addIndexes adds the LineItems into the indexes of Model
+func (model *Model) addIndexes(lis ...LineItem) {
+	for _, li := range lis {
+		for _, column := range model.Columns {
+			if !column.isIndexed() {
+				continue
+			}
+			if value, ok := li.Get(column.Name); ok {
+				model.index(column.Name).Add(value, li.ID())
+			}
+		}
+	}
+}
+
+// removeIndexes removes the LineItems from the indexes of Model
+func (model *Model) removeIndexes(lis ...LineItem) {
+	for _, li := range lis {
+		for _, column := range model.Columns {
+			if !column.isIndexed() {
+				continue
+			}
+			if value, ok := li.Get(column.Name); ok {
+				model.index(column.Name).Remove(value, li.ID())
+			}
+		}
+	}
+}
+
+// FindBy returns the LineItems whose value of the given column equals to the given value,
+// it uses the Index of the column if the column has one
+func (model *Model) FindBy(name string, value interface{}) LineItems {
+	return model.Where(map[string]interface{}{name: value})
+}
+
+// Where returns the LineItems sorted by id which match all the given conditions,
+// the key of conditions is column name and the value is the expected value,
+// it uses the smallest Index of conditions to narrow the candidates
+func (model *Model) Where(conditions map[string]interface{}) LineItems {
+	var candidates []float64
+	hasCandidates := false
+	for name, value := range conditions {
+		if name == "id" {
+			if id, ok := value.(float64); ok {
+				candidates, hasCandidates = []float64{id}, true
+				break
+			}
+		}
+
+		if index := model.index(name); index != nil && isIndexable(value) {
+			ids := index.Ids(value)
+			if !hasCandidates || len(ids) < len(candidates) {
+				candidates, hasCandidates = ids, true
+			}
+		}
+	}
+
+	var lis LineItems
+	if hasCandidates {
+		lis = LineItems{}
+		for _, id := range candidates {
+			if li, ok := model.Get(id); ok {
+				lis = append(lis, li)
+			}
+		}
+	} else {
+		lis = model.lineItems()
+	}
+
+	matched := LineItems{}
+	for _, li := range lis {
+		if li.Match(conditions) {
+			matched = append(matched, li)
+		}
+	}
+	return matched
+}
+
+// Match returns if the LineItem matches all the given conditions,
+// a *Range condition matches the values in the range
+func (li LineItem) Match(conditions map[string]interface{}) bool {
+	for name, expected := range conditions {
+		value, ok := li.Get(name)
+		if !ok {
+			return false
+		}
+
+		if r, isRange := expected.(*Range); isRange {
+			if !r.Contains(value) {
+				return false
+			}
+		} else if !reflect.DeepEqual(value, expected) {
+			return false
+		}
+	}
+	return true
+}
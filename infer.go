@@ -0,0 +1,369 @@
+package apifaker
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Infer allocates and returns new Models inferred from the given json payloads,
+// every payload must be an object or an array of objects, they are used as seeds:
+//  1. type of a Column is the json type of its values, null values are replaced by the zero value
+//  2. a string Column whose values are all different is unique
+//  3. a "xxx_id" Column uses number type, numeric strings are converted to numbers
+//  4. an array of objects with ids becomes a has_many resource, an object with id becomes a has_one resource,
+//     they are returned after the first Model with a "<singular name>_id" Column
+//  5. items without id get sequential ids, the latter item replaces the former one with the same id
+//  6. a "xxx_id" Column is dropped unless all its values are ids of the inferred resource "xxxs",
+//     so the Models pass CheckRelationships
+func Infer(name string, payloads ...[]byte) ([]*Model, error) {
+	rows := []map[string]interface{}{}
+	for i, payload := range payloads {
+		var value interface{}
+		if err := json.Unmarshal(payload, &value); err != nil {
+			return nil, SeedsErrorf("payload %d has wrong json format: %v", i, err)
+		}
+
+		payloadRows, ok := objectsOf(value)
+		if !ok {
+			return nil, SeedsErrorf("payload %d must be an object or an array of objects", i)
+		}
+		rows = append(rows, payloadRows...)
+	}
+
+	inferrer := &inferrer{models: map[string]*Model{}}
+	if _, err := inferrer.infer(name, rows); err != nil {
+		return nil, err
+	}
+	inferrer.dropDanglingForeignKeys()
+	return inferrer.sortedModels(name), nil
+}
+
+// objectsOf returns the objects of the value if it is an object or an array of objects
+func objectsOf(value interface{}) ([]map[string]interface{}, bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return []map[string]interface{}{v}, true
+	case []interface{}:
+		objects := []map[string]interface{}{}
+		for _, element := range v {
+			object, ok := element.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			objects = append(objects, object)
+		}
+		return objects, true
+	}
+	return nil, false
+}
+
+// hasIds returns if every object has an id
+func hasIds(objects []map[string]interface{}) bool {
+	for _, object := range objects {
+		if _, ok := object["id"]; !ok {
+			return false
+		}
+	}
+	return len(objects) > 0
+}
+
+// jsonTypeOf returns the JsonType of the value decoded from json
+func jsonTypeOf(value interface{}) JsonType {
+	switch value.(type) {
+	case bool:
+		return boolean
+	case float64:
+		return number
+	case string:
+		return str
+	case []interface{}:
+		return array
+	case map[string]interface{}:
+		return object
+	}
+	return ""
+}
+
+// zeroValueOf returns the zero value of the JsonType
+func zeroValueOf(jsonType JsonType) interface{} {
+	switch jsonType {
+	case boolean:
+		return false
+	case number:
+		return float64(0)
+	case str:
+		return ""
+	case array:
+		return []interface{}{}
+	}
+	return map[string]interface{}{}
+}
+
+// inferrer collects the Models inferred from nested objects
+type inferrer struct {
+	models map[string]*Model
+}
+
+// sortedModels returns the Model with the given name first, then others sorted by name
+func (in *inferrer) sortedModels(name string) []*Model {
+	names := []string{}
+	for modelName := range in.models {
+		if modelName != name {
+			names = append(names, modelName)
+		}
+	}
+	sort.Strings(names)
+
+	models := []*Model{in.models[name]}
+	for _, modelName := range names {
+		models = append(models, in.models[modelName])
+	}
+	return models
+}
+
+// infer infers the Model with the given name from rows, merges into the existing Model with the same name,
+// returns the ids of rows
+func (in *inferrer) infer(name string, rows []map[string]interface{}) ([]float64, error) {
+	model, ok := in.models[name]
+	if !ok {
+		model = NewModel(nil)
+		model.Name = name
+		model.HasMany = []string{}
+		model.HasOne = []string{}
+		in.models[name] = model
+	}
+
+	// extract nested resources
+	nested := map[string][]map[string]interface{}{}
+	nestedOwners := map[string][]int{}
+	hasOne := map[string]bool{}
+	for i, row := range rows {
+		for key, value := range row {
+			objects, ok := objectsOf(value)
+			if !ok || !hasIds(objects) {
+				continue
+			}
+			if _, isObject := value.(map[string]interface{}); isObject {
+				hasOne[key] = true
+			}
+			nested[key] = append(nested[key], objects...)
+			for range objects {
+				nestedOwners[key] = append(nestedOwners[key], i)
+			}
+		}
+	}
+
+	// assign ids, generated ids come after all explicit ids
+	// so that no row without id takes the id of a latter row
+	seeds := []map[string]interface{}{}
+	for _, row := range rows {
+		seed := map[string]interface{}{}
+		for key, value := range row {
+			if _, ok := nested[key]; !ok {
+				seed[key] = value
+			}
+		}
+
+		if id, ok := seed["id"]; ok {
+			idFloat64, ok := toNumber(id)
+			if !ok {
+				return nil, SeedsErrorf("id of resource %s must be a number: %v", name, id)
+			}
+			seed["id"] = idFloat64
+			model.updateId(idFloat64)
+		}
+		seeds = append(seeds, seed)
+	}
+
+	ids := []float64{}
+	for _, seed := range seeds {
+		if _, ok := seed["id"]; !ok {
+			seed["id"] = model.nextId()
+		}
+		ids = append(ids, seed["id"].(float64))
+	}
+
+	// nested resources
+	keys := []string{}
+	for key := range nested {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	foreignKey := fmt.Sprintf("%s_id", singular(name))
+	for _, key := range keys {
+		for i, object := range nested[key] {
+			object[foreignKey] = ids[nestedOwners[key][i]]
+		}
+
+		resName := key
+		if hasOne[key] {
+			resName = plural(key)
+			model.HasOne = appendUnique(model.HasOne, singular(key))
+		} else {
+			model.HasMany = appendUnique(model.HasMany, key)
+		}
+		if _, err := in.infer(resName, nested[key]); err != nil {
+			return nil, err
+		}
+	}
+
+	// the latter item replaces the former one with the same id
+	positions := map[interface{}]int{}
+	for i, seed := range model.Seeds {
+		positions[seed["id"]] = i
+	}
+	for _, seed := range seeds {
+		if i, ok := positions[seed["id"]]; ok {
+			model.Seeds[i] = seed
+		} else {
+			positions[seed["id"]] = len(model.Seeds)
+			model.Seeds = append(model.Seeds, seed)
+		}
+	}
+
+	if err := in.inferColumns(model); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// inferColumns infers Columns from Seeds, fills missing values with zero values
+func (in *inferrer) inferColumns(model *Model) error {
+	types := map[string]JsonType{}
+	for _, seed := range model.Seeds {
+		for key, value := range seed {
+			if strings.HasSuffix(key, "_id") {
+				if number, ok := toNumber(value); ok {
+					value = number
+					seed[key] = number
+				}
+			}
+
+			jsonType := jsonTypeOf(value)
+			if jsonType == "" {
+				continue
+			}
+			if existing, ok := types[key]; ok && existing != jsonType {
+				return ColumnsErrorf("column[name=\"%s\"] of resource %s has different types: %s and %s", key, model.Name, existing, jsonType)
+			}
+			types[key] = jsonType
+		}
+	}
+
+	names := []string{}
+	for key := range types {
+		if key != "id" {
+			names = append(names, key)
+		}
+	}
+	sort.Strings(names)
+
+	model.Columns = []*Column{{Name: "id", Type: number.Name()}}
+	for _, key := range names {
+		column := &Column{Name: key, Type: types[key].Name()}
+		column.Unique = types[key] == str && !strings.HasSuffix(key, "_id") && hasDistinctValues(model.Seeds, key)
+		model.Columns = append(model.Columns, column)
+	}
+
+	for _, seed := range model.Seeds {
+		for key := range seed {
+			if _, ok := types[key]; !ok {
+				delete(seed, key)
+			}
+		}
+		for key, jsonType := range types {
+			if value, ok := seed[key]; !ok || value == nil {
+				seed[key] = zeroValueOf(jsonType)
+			}
+		}
+	}
+	return nil
+}
+
+// dropDanglingForeignKeys removes the "xxx_id" Columns and their values which refer to no item of the resource "xxxs"
+func (in *inferrer) dropDanglingForeignKeys() {
+	for _, model := range in.models {
+		columns := []*Column{}
+		for _, column := range model.Columns {
+			if column.Name == "id" || !strings.HasSuffix(column.Name, "_id") || in.refersToItems(model, column.Name) {
+				columns = append(columns, column)
+				continue
+			}
+			for _, seed := range model.Seeds {
+				delete(seed, column.Name)
+			}
+		}
+		model.Columns = columns
+	}
+}
+
+// refersToItems returns if all values of the "xxx_id" key in Seeds of the model are ids of the resource "xxxs"
+func (in *inferrer) refersToItems(model *Model, key string) bool {
+	target, ok := in.models[plural(strings.TrimSuffix(key, "_id"))]
+	if !ok {
+		return false
+	}
+
+	ids := map[interface{}]bool{}
+	for _, seed := range target.Seeds {
+		ids[seed["id"]] = true
+	}
+	for _, seed := range model.Seeds {
+		if !ids[seed[key]] {
+			return false
+		}
+	}
+	return true
+}
+
+// hasDistinctValues returns if the non-empty values of the key in seeds are all different,
+// there must be two values at least
+func hasDistinctValues(seeds []map[string]interface{}, key string) bool {
+	values := map[interface{}]bool{}
+	for _, seed := range seeds {
+		value, ok := seed[key]
+		if !ok || value == nil || reflect.DeepEqual(value, "") || values[value] {
+			return false
+		}
+		values[value] = true
+	}
+	return len(values) > 1
+}
+
+// toNumber returns the float64 of a number or a numeric string
+func toNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case string:
+		number, err := strconv.ParseFloat(v, 64)
+		return number, err == nil
+	}
+	return 0, false
+}
+
+// appendUnique appends the element if the slice does not contain it
+func appendUnique(slice []string, element string) []string {
+	for _, e := range slice {
+		if e == element {
+			return slice
+		}
+	}
+	return append(slice, element)
+}
+
+// containsString returns if the slice contains the element
+func containsString(slice []string, element string) bool {
+	for _, e := range slice {
+		if e == element {
+			return true
+		}
+	}
+	return false
+}
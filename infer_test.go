@@ -0,0 +1,113 @@
+package apifaker
+
+import (
+	"encoding/json"
+	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInfer(t *testing.T) {
+	Describ("Infer", t, func() {
+		Context("when pass objects with nested resources", func() {
+			models, err := Infer("users",
+				[]byte(`{"id": 1, "name": "Frank", "age": 22, "books": [{"id": 1, "title": "A"}], "avatar": {"id": 1, "url": "a.png"}}`),
+				[]byte(`[{"id": "2", "name": "Tony", "age": null, "books": [{"id": 2, "title": "B"}]}]`))
+
+			It("returns the model and the nested models", func() {
+				Expect(err, ShouldBeNil)
+				Expect(len(models), ShouldEqual, 3)
+				Expect(models[0].Name, ShouldEqual, "users")
+				Expect(models[1].Name, ShouldEqual, "avatars")
+				Expect(models[2].Name, ShouldEqual, "books")
+			})
+
+			It("infers columns with id first", func() {
+				users := models[0]
+				Expect(len(users.Columns), ShouldEqual, 3)
+				Expect(*users.Columns[0], ShouldResemble, Column{Name: "id", Type: "number"})
+				Expect(*users.Columns[1], ShouldResemble, Column{Name: "age", Type: "number"})
+				Expect(*users.Columns[2], ShouldResemble, Column{Name: "name", Type: "string", Unique: true})
+				Expect(users.CheckColumnsMeta(), ShouldBeNil)
+			})
+
+			It("uses payloads as seeds", func() {
+				users := models[0]
+				Expect(users.Seeds, ShouldResemble, []map[string]interface{}{
+					{"id": float64(1), "name": "Frank", "age": float64(22)},
+					{"id": float64(2), "name": "Tony", "age": float64(0)},
+				})
+				Expect(users.HasMany, ShouldResemble, []string{"books"})
+				Expect(users.HasOne, ShouldResemble, []string{"avatar"})
+			})
+
+			It("adds foreign keys to the nested models", func() {
+				books := models[2]
+				Expect(books.Seeds[1], ShouldResemble, map[string]interface{}{
+					"id": float64(2), "title": "B", "user_id": float64(2),
+				})
+			})
+		})
+
+		Context("when a xxx_id column refers to no inferred resource", func() {
+			models, err := Infer("users",
+				[]byte(`[{"id": 1, "name": "Frank", "team_id": 3, "books": [{"id": 1, "title": "A", "publisher_id": "7"}]}]`))
+
+			It("drops the column", func() {
+				Expect(err, ShouldBeNil)
+				Expect(len(models[0].Columns), ShouldEqual, 2)
+				Expect(models[0].Seeds[0], ShouldResemble, map[string]interface{}{"id": float64(1), "name": "Frank"})
+				Expect(models[1].Seeds[0], ShouldResemble, map[string]interface{}{
+					"id": float64(1), "title": "A", "user_id": float64(1),
+				})
+			})
+
+			dir, _ := ioutil.TempDir("", "apifaker")
+			defer os.RemoveAll(dir)
+			for _, model := range models {
+				bytes, _ := json.MarshalIndent(model, "", "    ")
+				ioutil.WriteFile(filepath.Join(dir, model.Name+".json"), bytes, 0644)
+			}
+			faker, err := NewWithApiDir(dir)
+			It("writes files NewWithApiDir can load", func() {
+				Expect(err, ShouldBeNil)
+				Expect(faker.Routers["books"].Model.Len(), ShouldEqual, 1)
+			})
+		})
+
+		Context("when items have no id", func() {
+			models, err := Infer("tags", []byte(`[{"name": "go"}, {"name": "go"}]`))
+			It("assigns sequential ids", func() {
+				Expect(err, ShouldBeNil)
+				Expect(models[0].Seeds[1]["id"], ShouldEqual, float64(2))
+				Expect(models[0].Columns[1].Unique, ShouldBeFalse)
+			})
+		})
+
+		Context("when an item without id comes before an item with id", func() {
+			models, err := Infer("tags", []byte(`[{"name": "go"}, {"id": 1, "name": "rust"}]`))
+			It("keeps both items", func() {
+				Expect(err, ShouldBeNil)
+				Expect(len(models[0].Seeds), ShouldEqual, 2)
+				Expect(models[0].Seeds[0]["id"], ShouldEqual, float64(2))
+				Expect(models[0].Seeds[1]["id"], ShouldEqual, float64(1))
+			})
+		})
+
+		Context("when a column has different types", func() {
+			_, err := Infer("tags", []byte(`[{"name": "go"}, {"name": 1}]`))
+			It("returns error", func() {
+				Expect(err, ShouldNotBeNil)
+			})
+		})
+
+		Context("when payload is not an object or an array of objects", func() {
+			_, err := Infer("tags", []byte(`[1, 2]`))
+			It("returns error", func() {
+				Expect(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
@@ -0,0 +1,148 @@
+package apifaker
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultJournalSize the default max number of requests in the journal
+const defaultJournalSize = 1000
+
+// JournalEntry a request served by ApiFaker and its response status
+type JournalEntry struct {
+	RecordedRequest
+	Headers http.Header `json:"headers,omitempty"`
+
+	// Resource the name of the resource serves the request, empty if no resource serves it
+	Resource string `json:"resource,omitempty"`
+
+	Status int       `json:"status"`
+	Time   time.Time `json:"time"`
+}
+
+// Param returns the value of the given name in the form or json body, or in the query
+func (entry JournalEntry) Param(name string) string {
+	contentType := entry.Headers.Get("Content-Type")
+	switch {
+	case strings.HasPrefix(contentType, "application/x-www-form-urlencoded"):
+		if form, err := url.ParseQuery(entry.Body); err == nil && form.Get(name) != "" {
+			return form.Get(name)
+		}
+	case strings.HasPrefix(contentType, "application/json"):
+		body := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(entry.Body), &body); err == nil && body[name] != nil {
+			if value, ok := body[name].(string); ok {
+				return value
+			}
+			bytes, _ := json.Marshal(body[name])
+			return string(bytes)
+		}
+	}
+
+	query, _ := url.ParseQuery(entry.Query)
+	return query.Get(name)
+}
+
+// JournalEntries a list of JournalEntry from the oldest to the newest
+type JournalEntries []JournalEntry
+
+// Filter returns the JournalEntries which match the given func
+func (entries JournalEntries) Filter(match func(JournalEntry) bool) JournalEntries {
+	matched := JournalEntries{}
+	for _, entry := range entries {
+		if match(entry) {
+			matched = append(matched, entry)
+		}
+	}
+	return matched
+}
+
+// Requests returns the recorded requests from the oldest to the newest
+func (af *ApiFaker) Requests() JournalEntries {
+	af.journalLock.Lock()
+	defer af.journalLock.Unlock()
+
+	entries := JournalEntries{}
+	entries = append(entries, af.journal[af.journalNext:]...)
+	return append(entries, af.journal[:af.journalNext]...)
+}
+
+// ClearRequests removes all recorded requests
+func (af *ApiFaker) ClearRequests() {
+	af.journalLock.Lock()
+	defer af.journalLock.Unlock()
+
+	af.journal = nil
+	af.journalNext = 0
+}
+
+// recordRequest adds the JournalEntry into the journal, the oldest one is overwritten if the journal is full
+func (af *ApiFaker) recordRequest(entry JournalEntry) {
+	af.journalLock.Lock()
+	defer af.journalLock.Unlock()
+
+	size := af.JournalSize
+	if size == 0 {
+		size = defaultJournalSize
+	}
+	if size < 0 {
+		return
+	}
+
+	if len(af.journal) < size {
+		af.journal = append(af.journal, entry)
+		return
+	}
+	af.journal[af.journalNext] = entry
+	af.journalNext = (af.journalNext + 1) % len(af.journal)
+}
+
+// JournalMiddleware returns a gin.HandlerFunc which records every request except the admin apis into the journal
+func JournalMiddleware(faker *ApiFaker) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if faker.JournalSize < 0 || strings.HasPrefix(ctx.Request.URL.Path, faker.Prefix+adminPath+"/") {
+			return
+		}
+
+		entry := JournalEntry{
+			RecordedRequest: recordedRequestOf(ctx.Request),
+			Headers:         ctx.Request.Header.Clone(),
+			Time:            time.Now(),
+		}
+		if router, ok := faker.routerOfPath(ctx.Request.URL.Path); ok {
+			entry.Resource = router.Model.Name
+		}
+
+		ctx.Next()
+
+		entry.Status = ctx.Writer.Status()
+		faker.recordRequest(entry)
+	}
+}
+
+// setJournalHandlers sets the admin apis of the journal:
+//
+//	GET    /_apifaker/requests, filtered by the method, path and resource query params
+//	DELETE /_apifaker/requests
+func (af *ApiFaker) setJournalHandlers() {
+	path := af.Prefix + adminPath + "/requests"
+
+	af.GET(path, func(ctx *gin.Context) {
+		method, path, resource := ctx.Query("method"), ctx.Query("path"), ctx.Query("resource")
+		ctx.JSON(http.StatusOK, af.Requests().Filter(func(entry JournalEntry) bool {
+			return (method == "" || strings.EqualFold(method, entry.Method)) &&
+				(path == "" || path == entry.Path) &&
+				(resource == "" || resource == entry.Resource)
+		}))
+	})
+
+	af.DELETE(path, func(ctx *gin.Context) {
+		af.ClearRequests()
+		ctx.JSON(http.StatusOK, nil)
+	})
+}
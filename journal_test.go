@@ -0,0 +1,75 @@
+package apifaker
+
+import (
+	"encoding/json"
+	. "github.com/smartystreets/goconvey/convey"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestJournal(t *testing.T) {
+	faker, _ := NewWithApiDir(testDir)
+	faker.InMemory = true
+	request := func(method, path string, form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rw := httptest.NewRecorder()
+		faker.ServeHTTP(rw, req)
+		return rw
+	}
+
+	Describ("Requests", t, func() {
+		faker.ClearRequests()
+		request("GET", "/users/1?fields=name", nil)
+		request("POST", "/books", url.Values{"title": {"journal"}, "user_id": {"1"}})
+		request("GET", "/nothing", nil)
+		request("GET", "/_apifaker/faults", nil)
+
+		It("records every request except the admin apis", func() {
+			requests := faker.Requests()
+			Expect(len(requests), ShouldEqual, 3)
+			Expect(requests[0].Resource, ShouldEqual, "users")
+			Expect(requests[0].Param("fields"), ShouldEqual, "name")
+			Expect(requests[0].Status, ShouldEqual, http.StatusOK)
+			Expect(requests[2].Resource, ShouldEqual, "")
+			Expect(requests[2].Status, ShouldEqual, http.StatusNotFound)
+		})
+
+		It("filters the requests", func() {
+			posts := faker.Requests().Filter(func(entry JournalEntry) bool {
+				return entry.Method == "POST" && entry.Path == "/books" && entry.Param("user_id") == "1"
+			})
+			Expect(len(posts), ShouldEqual, 1)
+			Expect(posts[0].Param("title"), ShouldEqual, "journal")
+		})
+
+		It("serves the admin apis", func() {
+			entries := []JournalEntry{}
+			json.Unmarshal(request("GET", "/_apifaker/requests?method=post&resource=books", nil).Body.Bytes(), &entries)
+			Expect(len(entries), ShouldEqual, 1)
+			Expect(entries[0].Path, ShouldEqual, "/books")
+
+			Expect(request("DELETE", "/_apifaker/requests", nil).Code, ShouldEqual, http.StatusOK)
+			Expect(len(faker.Requests()), ShouldEqual, 0)
+		})
+	})
+
+	Describ("JournalSize", t, func() {
+		faker.ClearRequests()
+		faker.JournalSize = 2
+		defer func() { faker.JournalSize = 0 }()
+
+		It("keeps the newest requests", func() {
+			request("GET", "/users/1", nil)
+			request("GET", "/users/2", nil)
+			request("GET", "/users/3", nil)
+			requests := faker.Requests()
+			Expect(len(requests), ShouldEqual, 2)
+			Expect(requests[0].Path, ShouldEqual, "/users/2")
+			Expect(requests[1].Path, ShouldEqual, "/users/3")
+		})
+	})
+}
@@ -0,0 +1,259 @@
+package apifaker
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// jwksPath the path of the JSON Web Key Set
+	jwksPath = "/.well-known/jwks.json"
+
+	// discoveryPath the path of the OIDC discovery document
+	discoveryPath = "/.well-known/openid-configuration"
+
+	jwtAlgorithm = "RS256"
+)
+
+// signingKey the locally generated key to sign JWTs
+type signingKey struct {
+	id         string
+	privateKey *rsa.PrivateKey
+}
+
+// newSigningKey generates a new RSA key
+func newSigningKey() (*signingKey, error) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, AuthErrorf("can not generate the signing key: %v", err)
+	}
+	return &signingKey{id: randomToken()[:16], privateKey: privateKey}, nil
+}
+
+// jwk returns the public key in JSON Web Key format
+func (key *signingKey) jwk() map[string]interface{} {
+	publicKey := key.privateKey.PublicKey
+	return map[string]interface{}{
+		"kty": "RSA",
+		"use": "sig",
+		"alg": jwtAlgorithm,
+		"kid": key.id,
+		"n":   base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes()),
+	}
+}
+
+// sign returns the signed JWT of the claims
+func (key *signingKey) sign(claims map[string]interface{}) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": jwtAlgorithm, "typ": "JWT", "kid": key.id})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key.privateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// verify verifies the signature of the JWT, returns its claims
+func (key *signingKey) verify(token string) (map[string]interface{}, error) {
+	pieces := strings.Split(token, ".")
+	if len(pieces) != 3 {
+		return nil, fmt.Errorf("malformed jwt")
+	}
+
+	header := map[string]string{}
+	headerBytes, err := base64.RawURLEncoding.DecodeString(pieces[0])
+	if err == nil {
+		err = json.Unmarshal(headerBytes, &header)
+	}
+	if err != nil || header["alg"] != jwtAlgorithm || header["kid"] != key.id {
+		return nil, fmt.Errorf("unknown jwt header")
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(pieces[2])
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256([]byte(pieces[0] + "." + pieces[1]))
+	if err := rsa.VerifyPKCS1v15(&key.privateKey.PublicKey, crypto.SHA256, digest[:], signature); err != nil {
+		return nil, err
+	}
+
+	claims := map[string]interface{}{}
+	payload, err := base64.RawURLEncoding.DecodeString(pieces[1])
+	if err == nil {
+		err = json.Unmarshal(payload, &claims)
+	}
+	return claims, err
+}
+
+// issuer returns Issuer or the base url of the request
+func (auth *Auth) issuer(req *http.Request) string {
+	if auth.Issuer != "" {
+		return auth.Issuer
+	}
+
+	scheme := "http"
+	if req.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + req.Host + auth.apiFaker.Prefix
+}
+
+// signJWT returns the signed JWT of the Identity
+func (auth *Auth) signJWT(req *http.Request, identity *Identity) (string, error) {
+	now := time.Now()
+	claims := map[string]interface{}{
+		"iss": auth.issuer(req),
+		"sub": identity.Subject,
+		"iat": now.Unix(),
+		"exp": now.Add(time.Duration(auth.TokenTTL) * time.Second).Unix(),
+	}
+	if auth.Audience != "" {
+		claims["aud"] = auth.Audience
+	}
+	if identity.Role != "" {
+		claims["role"] = identity.Role
+	}
+	if len(identity.Scopes) > 0 {
+		claims["scope"] = strings.Join(identity.Scopes, " ")
+	}
+	for claim, column := range auth.Claims {
+		if value, ok := identity.User[column]; ok {
+			claims[claim] = value
+		}
+	}
+
+	return auth.key.sign(claims)
+}
+
+// identityOfJWT verifies the signature, the expiry, the issuer and the audience of the JWT,
+// returns nil if the JWT is invalid
+func (auth *Auth) identityOfJWT(req *http.Request, token string) *Identity {
+	claims, err := auth.key.verify(token)
+	if err != nil {
+		return nil
+	}
+
+	exp, ok := claims["exp"].(float64)
+	if !ok || time.Now().Unix() >= int64(exp) {
+		return nil
+	}
+	if claims["iss"] != auth.issuer(req) {
+		return nil
+	}
+	if auth.Audience != "" && claims["aud"] != auth.Audience {
+		return nil
+	}
+
+	identity := &Identity{Claims: claims}
+	identity.Subject, _ = claims["sub"].(string)
+	identity.Role, _ = claims["role"].(string)
+	if scope, ok := claims["scope"].(string); ok {
+		identity.Scopes = strings.Fields(scope)
+	}
+	if model, err := auth.apiFaker.modelOf(auth.UserResource); err == nil {
+		if id, ok := toNumber(identity.Subject); ok {
+			if li, ok := model.Get(id); ok {
+				identity.User = li.ToMap()
+			}
+		}
+	}
+	return identity
+}
+
+// setJWTHandlers sets the JWKS endpoint and the OIDC discovery endpoint
+func (af *ApiFaker) setJWTHandlers() {
+	auth := af.Auth
+	af.GET(af.Prefix+jwksPath, func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, map[string]interface{}{"keys": []interface{}{auth.key.jwk()}})
+	})
+
+	af.GET(af.Prefix+discoveryPath, func(ctx *gin.Context) {
+		scheme := "http"
+		if ctx.Request.TLS != nil {
+			scheme = "https"
+		}
+		base := scheme + "://" + ctx.Request.Host + af.Prefix
+
+		ctx.JSON(http.StatusOK, map[string]interface{}{
+			"issuer":                                auth.issuer(ctx.Request),
+			"jwks_uri":                              base + jwksPath,
+			"token_endpoint":                        base + tokenPath,
+			"grant_types_supported":                 []string{"password", "refresh_token"},
+			"response_types_supported":              []string{"token"},
+			"subject_types_supported":               []string{"public"},
+			"id_token_signing_alg_values_supported": []string{jwtAlgorithm},
+			"scopes_supported":                      auth.scopesSupported(),
+			"claims_supported":                      auth.claimsSupported(),
+		})
+	})
+}
+
+// scopesSupported returns the scopes required by any api
+func (auth *Auth) scopesSupported() []string {
+	scopes := []string{}
+	for _, ruleScopes := range auth.Scopes {
+		for _, scope := range ruleScopes {
+			scopes = appendUnique(scopes, scope)
+		}
+	}
+	sort.Strings(scopes)
+	return scopes
+}
+
+// claimsSupported returns the names of claims in JWTs
+func (auth *Auth) claimsSupported() []string {
+	claims := []string{}
+	for claim := range auth.Claims {
+		claims = append(claims, claim)
+	}
+	sort.Strings(claims)
+	return append([]string{"iss", "sub", "aud", "iat", "exp", "role", "scope"}, claims...)
+}
+
+// requiredScopes returns the scopes required by the api of the method and resource
+func (auth *Auth) requiredScopes(method, resource string) []string {
+	scopes := []string{}
+	for rule, ruleScopes := range auth.Scopes {
+		if matchRules([]string{rule}, method, resource) {
+			scopes = append(scopes, ruleScopes...)
+		}
+	}
+	return scopes
+}
+
+// hasScopes returns if the Identity has all the scopes
+func (identity *Identity) hasScopes(scopes []string) bool {
+	granted := map[string]bool{}
+	for _, scope := range identity.Scopes {
+		granted[scope] = true
+	}
+	for _, scope := range scopes {
+		if !granted[scope] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,129 @@
+package apifaker
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	. "github.com/smartystreets/goconvey/convey"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJWT(t *testing.T) {
+	dir := newAuthTestDir()
+	defer os.RemoveAll(dir)
+
+	faker, _ := NewWithApiDir(dir)
+	faker.InMemory = true
+	request := func(method, path string, form url.Values, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		for key := range header {
+			req.Header.Set(key, header.Get(key))
+		}
+		rw := httptest.NewRecorder()
+		faker.ServeHTTP(rw, req)
+		return rw
+	}
+	decode := func(response *httptest.ResponseRecorder) map[string]interface{} {
+		data := map[string]interface{}{}
+		json.Unmarshal(response.Body.Bytes(), &data)
+		return data
+	}
+	bearer := func(token string) http.Header {
+		return http.Header{"Authorization": {"Bearer " + token}}
+	}
+
+	err := faker.SetAuth(&Auth{
+		JWT:          true,
+		Audience:     "api",
+		UserResource: "accounts",
+		RoleColumn:   "role",
+		Claims:       map[string]string{"name": "name"},
+		Scopes:       map[string][]string{"POST posts": {"posts:write"}},
+	})
+
+	Describ("SetAuth with JWT", t, func() {
+		It("enables bearer tokens", func() {
+			Expect(err, ShouldBeNil)
+			Expect(faker.Auth.Bearer, ShouldBeTrue)
+			Expect(faker.SetAuth(&Auth{JWT: true, UserResource: "accounts", Claims: map[string]string{"x": "foo"}}), ShouldNotBeNil)
+		})
+	})
+
+	Describ("JWKS and discovery", t, func() {
+		It("serves the public key", func() {
+			keys := decode(request("GET", jwksPath, nil, nil))["keys"].([]interface{})
+			Expect(len(keys), ShouldEqual, 1)
+			Expect(keys[0].(map[string]interface{})["alg"], ShouldEqual, "RS256")
+		})
+
+		It("serves the discovery document", func() {
+			document := decode(request("GET", discoveryPath, nil, nil))
+			Expect(document["issuer"], ShouldEqual, "http://example.com")
+			Expect(document["jwks_uri"], ShouldEqual, "http://example.com"+jwksPath)
+			Expect(document["scopes_supported"], ShouldResemble, []interface{}{"posts:write"})
+		})
+	})
+
+	Describ("JWT", t, func() {
+		response := request("POST", "/auth/token", url.Values{"username": {"frank"}, "password": {"secret"}}, nil)
+		token, _ := decode(response)["access_token"].(string)
+
+		It("contains the claims", func() {
+			Expect(response.Code, ShouldEqual, http.StatusOK)
+			claims, err := faker.Auth.key.verify(token)
+			Expect(err, ShouldBeNil)
+			Expect(claims["sub"], ShouldEqual, "1")
+			Expect(claims["role"], ShouldEqual, "admin")
+			Expect(claims["name"], ShouldEqual, "frank")
+			Expect(claims["aud"], ShouldEqual, "api")
+		})
+
+		It("authenticates the valid token", func() {
+			Expect(request("GET", "/accounts/1", nil, bearer(token)).Code, ShouldEqual, http.StatusOK)
+		})
+
+		It("rejects the tampered token", func() {
+			pieces := strings.Split(token, ".")
+			claims, _ := json.Marshal(map[string]interface{}{"sub": "2", "exp": time.Now().Add(time.Hour).Unix()})
+			tampered := pieces[0] + "." + base64.RawURLEncoding.EncodeToString(claims) + "." + pieces[2]
+			Expect(request("GET", "/accounts/1", nil, bearer(tampered)).Code, ShouldEqual, http.StatusUnauthorized)
+		})
+
+		It("rejects the expired token", func() {
+			expired, _ := faker.Auth.key.sign(map[string]interface{}{
+				"iss": "http://example.com", "sub": "1", "aud": "api", "exp": time.Now().Add(-time.Minute).Unix(),
+			})
+			Expect(request("GET", "/accounts/1", nil, bearer(expired)).Code, ShouldEqual, http.StatusUnauthorized)
+		})
+
+		It("rejects the token of another audience", func() {
+			other, _ := faker.Auth.key.sign(map[string]interface{}{
+				"iss": "http://example.com", "sub": "1", "aud": "web", "exp": time.Now().Add(time.Minute).Unix(),
+			})
+			Expect(request("GET", "/accounts/1", nil, bearer(other)).Code, ShouldEqual, http.StatusUnauthorized)
+		})
+	})
+
+	Describ("Scopes", t, func() {
+		post := url.Values{"title": {"c"}, "account_id": {"1"}}
+
+		It("responses 403 without the required scopes", func() {
+			token, _ := decode(request("POST", "/auth/token", url.Values{"username": {"tony"}, "password": {"jarvis"}}, nil))["access_token"].(string)
+			response := request("POST", "/posts", post, bearer(token))
+			Expect(response.Code, ShouldEqual, http.StatusForbidden)
+			Expect(response.Header().Get("WWW-Authenticate"), ShouldContainSubstring, "insufficient_scope")
+		})
+
+		It("grants the requested scopes", func() {
+			form := url.Values{"username": {"tony"}, "password": {"jarvis"}, "scope": {"posts:write"}}
+			token, _ := decode(request("POST", "/auth/token", form, nil))["access_token"].(string)
+			Expect(request("POST", "/posts", post, bearer(token)).Code, ShouldEqual, http.StatusOK)
+		})
+	})
+}
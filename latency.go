@@ -0,0 +1,170 @@
+package apifaker
+
+import (
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	uniformDistribution = "uniform"
+	normalDistribution  = "normal"
+
+	// delayQueryParam the query param overrides the latency of a request in milliseconds
+	delayQueryParam = "_delay"
+)
+
+// Latency describes how long to wait before handling a request
+type Latency struct {
+	// Fixed the fixed delay in milliseconds
+	Fixed float64 `json:"fixed"`
+
+	// Jitter the random delay added to Fixed in milliseconds,
+	// it is the range [-Jitter, Jitter] for "uniform" and the standard deviation for "normal"
+	Jitter float64 `json:"jitter"`
+
+	// Distribution the distribution of Jitter, "uniform"(default) or "normal"
+	Distribution string `json:"distribution"`
+
+	// Methods overrides the Latency for the given methods, the key is a method name like "GET"
+	Methods map[string]*Latency `json:"methods"`
+}
+
+// CheckMeta checks
+//  1. Fixed and Jitter must not be negative
+//  2. Distribution must be "uniform" or "normal"
+//  3. every key of Methods must be a RestMethod, the keys are upper cased
+func (latency *Latency) CheckMeta() error {
+	if latency.Fixed < 0 || latency.Jitter < 0 {
+		return LatencyErrorf("fixed and jitter must not be negative: %v, %v", latency.Fixed, latency.Jitter)
+	}
+
+	switch latency.Distribution {
+	case "", uniformDistribution, normalDistribution:
+	default:
+		return LatencyErrorf("unsupportted distribution: %s, all supportted distributions: [%s %s]",
+			latency.Distribution, uniformDistribution, normalDistribution)
+	}
+
+	methods := map[string]*Latency{}
+	for name, methodLatency := range latency.Methods {
+		method, err := ParseRestMethod(name)
+		if err != nil {
+			return LatencyErrorf("%v", err)
+		}
+		if _, ok := methods[method.String()]; ok {
+			return LatencyErrorf("method %s has been used", method)
+		}
+		if err := methodLatency.CheckMeta(); err != nil {
+			return err
+		}
+		methods[method.String()] = methodLatency
+	}
+	if latency.Methods != nil {
+		latency.Methods = methods
+	}
+
+	return nil
+}
+
+// Duration returns a random delay for the given method
+func (latency *Latency) Duration(method string) time.Duration {
+	if methodLatency, ok := latency.Methods[method]; ok && methodLatency != nil {
+		return methodLatency.Duration(method)
+	}
+
+	delay := latency.Fixed
+	if latency.Jitter > 0 {
+		switch latency.Distribution {
+		case normalDistribution:
+			delay += rand.NormFloat64() * latency.Jitter
+		default:
+			delay += (rand.Float64()*2 - 1) * latency.Jitter
+		}
+	}
+
+	if delay < 0 {
+		return 0
+	}
+	return time.Duration(delay * float64(time.Millisecond))
+}
+
+// SetLatency sets the Latency of the resource with the given name,
+// an empty name sets the default Latency of all resources,
+// a nil latency removes the Latency, the Latency of a resource is never saved into its json file
+func (af *ApiFaker) SetLatency(name string, latency *Latency) error {
+	if latency != nil {
+		if err := latency.CheckMeta(); err != nil {
+			return err
+		}
+	}
+
+	af.latencyLock.Lock()
+	defer af.latencyLock.Unlock()
+
+	if name == "" {
+		af.Latency = latency
+		return nil
+	}
+
+	router, ok := af.Routers[name]
+	if !ok {
+		return JsonFileErrorf("unknown resource: %s", name)
+	}
+	router.Model.runtimeLatency = latency
+	router.Model.runtimeLatencySet = true
+	return nil
+}
+
+// currentLatency returns the Latency set by ApiFaker.SetLatency if it has been set, otherwise the loaded Latency
+func (model *Model) currentLatency() *Latency {
+	if model.runtimeLatencySet {
+		return model.runtimeLatency
+	}
+	return model.Latency
+}
+
+// latencyOf returns the delay of the given request,
+// the query param "_delay" has the highest priority, then the resource's Latency,
+// the default Latency at last
+func (af *ApiFaker) latencyOf(ctx *gin.Context) time.Duration {
+	if delay := ctx.Query(delayQueryParam); delay != "" {
+		if ms, err := strconv.ParseFloat(delay, 64); err == nil && ms > 0 {
+			return time.Duration(ms * float64(time.Millisecond))
+		}
+	}
+
+	af.latencyLock.RLock()
+	defer af.latencyLock.RUnlock()
+
+	if router, ok := af.routerOfPath(ctx.Request.URL.Path); ok {
+		if latency := router.Model.currentLatency(); latency != nil {
+			return latency.Duration(ctx.Request.Method)
+		}
+	}
+	if af.Latency != nil {
+		return af.Latency.Duration(ctx.Request.Method)
+	}
+	return 0
+}
+
+// LatencyMiddleware returns a gin.HandlerFunc which waits for the latency of every request,
+// it stops waiting if the request has been canceled
+func LatencyMiddleware(faker *ApiFaker) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		delay := faker.latencyOf(ctx)
+		if delay <= 0 {
+			return
+		}
+
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Request.Context().Done():
+			ctx.Abort()
+		}
+	}
+}
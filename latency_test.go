@@ -0,0 +1,88 @@
+package apifaker
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLatency(t *testing.T) {
+	Describ("CheckMeta", t, func() {
+		Context("when distribution is unknown", func() {
+			It("returns error", func() {
+				Expect((&Latency{Distribution: "poisson"}).CheckMeta(), ShouldNotBeNil)
+			})
+		})
+		Context("when method is unknown", func() {
+			It("returns error", func() {
+				Expect((&Latency{Methods: map[string]*Latency{"HEAD": {}}}).CheckMeta(), ShouldNotBeNil)
+			})
+		})
+		Context("when method is lower case", func() {
+			latency := &Latency{Methods: map[string]*Latency{"post": {Fixed: 300}}}
+			It("upper cases the method", func() {
+				Expect(latency.CheckMeta(), ShouldBeNil)
+				Expect(latency.Duration("POST"), ShouldEqual, 300*time.Millisecond)
+			})
+			It("returns error if the method has been used", func() {
+				Expect((&Latency{Methods: map[string]*Latency{"post": {}, "POST": {}}}).CheckMeta(), ShouldNotBeNil)
+			})
+		})
+		Context("when fixed is negative", func() {
+			It("returns error", func() {
+				Expect((&Latency{Fixed: -1}).CheckMeta(), ShouldNotBeNil)
+			})
+		})
+	})
+
+	Describ("Duration", t, func() {
+		latency := &Latency{Fixed: 100, Jitter: 20, Methods: map[string]*Latency{"POST": {Fixed: 300}}}
+		It("returns a delay in the range of jitter", func() {
+			for i := 0; i < 100; i++ {
+				delay := latency.Duration("GET")
+				Expect(delay, ShouldBeBetweenOrEqual, 80*time.Millisecond, 120*time.Millisecond)
+			}
+		})
+		It("uses the latency of method if it is present", func() {
+			Expect(latency.Duration("POST"), ShouldEqual, 300*time.Millisecond)
+		})
+		It("never returns a negative delay", func() {
+			latency := &Latency{Jitter: 50, Distribution: normalDistribution}
+			for i := 0; i < 100; i++ {
+				Expect(latency.Duration("GET"), ShouldBeGreaterThanOrEqualTo, 0)
+			}
+		})
+	})
+
+	Describ("LatencyMiddleware", t, func() {
+		faker, _ := NewWithApiDir(testDir)
+		request := func(path string) time.Duration {
+			start := time.Now()
+			faker.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+			return time.Since(start)
+		}
+
+		Context("when the resource has a latency", func() {
+			err := faker.SetLatency("users", &Latency{Fixed: 30})
+			It("waits before responding", func() {
+				Expect(err, ShouldBeNil)
+				Expect(request("/users/1"), ShouldBeGreaterThanOrEqualTo, 30*time.Millisecond)
+				Expect(request("/books/1"), ShouldBeLessThan, 30*time.Millisecond)
+			})
+		})
+
+		Context("when pass the _delay query param", func() {
+			It("waits for the given milliseconds", func() {
+				Expect(request("/books/1?_delay=30"), ShouldBeGreaterThanOrEqualTo, 30*time.Millisecond)
+			})
+		})
+
+		Context("when set an unknown resource", func() {
+			It("returns error", func() {
+				Expect(faker.SetLatency("foo", &Latency{}), ShouldNotBeNil)
+			})
+		})
+	})
+}
@@ -1,12 +1,11 @@
 package apifaker
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/gin-gonic/gin"
-
-	"github.com/jinzhu/inflection"
-	"sort"
 	"strconv"
+	"time"
 )
 
 type LineItem struct {
@@ -20,16 +19,16 @@ func NewLineItemWithMap(dataMap map[string]interface{}) LineItem {
 }
 
 // NewLineItemWithGinContext allocates and returns a new LineItem,
-// its keys are from Model.Cloumns, values are from gin.Contex.PostForm(),
+// its keys are from Model.Cloumns, values are from gin.Contex.PostForm() named as the columns or the keys renamed by Transform,
 // error will be not nil if gin.Contex.PostForm() has no value for any key
 func NewLineItemWithGinContext(ctx *gin.Context, model *Model) (LineItem, error) {
 	li := LineItem{make(map[string]interface{})}
 	for _, column := range model.Columns {
-		// skip id column
-		if column.Name == "id" {
+		// skip id column and auto columns
+		if column.Name == "id" || column.isAuto() {
 			continue
 		}
-		if value := ctx.PostForm(column.Name); value != "" {
+		if value := model.formValue(ctx, column.Name); value != "" {
 			li.SetStringValue(column.Name, value, column.Type)
 		} else {
 			return li, fmt.Errorf("doesn't has column: %s", column.Name)
@@ -39,6 +38,63 @@ func NewLineItemWithGinContext(ctx *gin.Context, model *Model) (LineItem, error)
 	return li, nil
 }
 
+// NewConditionsWithGinContext allocates and returns a new conditions map for Model.Where,
+// its keys are the query params named as one of Model.Cloumns or the keys renamed by Transform,
+// its values are formatted by the type of the column,
+// query params with suffix _gt, _gte, _lt or _lte of number and datetime columns become a *Range
+func NewConditionsWithGinContext(ctx *gin.Context, model *Model) (map[string]interface{}, error) {
+	conditions := map[string]interface{}{}
+	for _, column := range model.Columns {
+		queryValue, ok := model.queryValue(ctx, column.Name)
+		if !ok {
+			continue
+		}
+
+		value, err := FormatValue(column.Type, queryValue)
+		if err != nil {
+			return conditions, fmt.Errorf("wrong value of query param %s: %v", column.Name, err)
+		}
+		conditions[column.Name] = value
+	}
+
+	// range filters of number and datetime columns, like created_at_gt
+	for _, column := range model.Columns {
+		if column.Type != number.Name() && column.Type != datetime.Name() {
+			continue
+		}
+
+		var r *Range
+		for _, operator := range rangeOperators {
+			queryValue, ok := model.queryValue(ctx, column.Name+operator)
+			if !ok {
+				continue
+			}
+
+			value, err := FormatValue(column.Type, queryValue)
+			if err == nil && column.Type == datetime.Name() {
+				_, err = time.Parse(time.RFC3339, queryValue)
+			}
+			if err != nil {
+				return conditions, fmt.Errorf("wrong value of query param %s: %v", column.Name+operator, err)
+			}
+			if r == nil {
+				r = &Range{}
+			}
+			r.set(operator, value)
+		}
+
+		if r != nil {
+			// the value of the equality filter is the only one in the range
+			if value, ok := conditions[column.Name]; ok {
+				r.Gte, r.Lte = value, value
+			}
+			conditions[column.Name] = r
+		}
+	}
+
+	return conditions, nil
+}
+
 // ID returns the float64 of id
 func (li *LineItem) ID() float64 {
 	return li.Id().(float64)
@@ -88,27 +144,23 @@ func (li *LineItem) SetStringValue(key, value, valueType string) error {
 
 // InsertRelatedData allocates and returns a new LineItem,
 // it will has all data of the caller LineItem,
-// it will insert all related data if the given Model's has any Column named xxx_id
+// it will insert all related data transformed by their Model if the given Model's has any Column named xxx_id,
+// the soft deleted related data is skipped
 func (li LineItem) InsertRelatedData(model *Model) LineItem {
 	// has one relationship
 	newLi := NewLineItemWithMap(li.ToMap())
-	singularName := inflection.Singular(model.Name)
+	singularName := singular(model.Name)
 	// HasOne
 	for _, resName := range model.HasOne {
 		resStruct := map[string]interface{}{}
-		if resRouter, ok := model.router.apiFaker.Routers[inflection.Plural(resName)]; ok {
-			resLis := resRouter.Model.ToLineItems()
-			sort.Sort(resLis)
-			for _, resLi := range resLis {
-				curId, ok := resLi.Get(fmt.Sprintf("%s_id", singularName))
-				if ok && curId == newLi.Id() {
-					resStruct = resLi.ToMap()
-					break
-				}
+		if resRouter, ok := model.router.apiFaker.Routers[plural(resName)]; ok {
+			resLis := resRouter.Model.FindBy(fmt.Sprintf("%s_id", singularName), newLi.Id()).withoutDeleted()
+			if len(resLis) > 0 {
+				resStruct = resRouter.Model.transform(resLis[0].InsertRelatedData(resRouter.Model))
 			}
 		}
 		if len(resStruct) > 0 {
-			newLi.Set(inflection.Singular(resName), resStruct)
+			newLi.Set(singular(resName), resStruct)
 		}
 	}
 
@@ -116,13 +168,9 @@ func (li LineItem) InsertRelatedData(model *Model) LineItem {
 	for _, resName := range model.HasMany {
 		resSlice := []interface{}{}
 		if resRouter, ok := model.router.apiFaker.Routers[resName]; ok {
-			resLis := resRouter.Model.ToLineItems()
-			sort.Sort(resLis)
+			resLis := resRouter.Model.FindBy(fmt.Sprintf("%s_id", singularName), newLi.Id()).withoutDeleted()
 			for _, resLi := range resLis {
-				curId, ok := resLi.Get(fmt.Sprintf("%s_id", singularName))
-				if ok && curId == newLi.Id() {
-					resSlice = append(resSlice, resLi.ToMap())
-				}
+				resSlice = append(resSlice, resRouter.Model.transform(resLi.InsertRelatedData(resRouter.Model)))
 			}
 		}
 		if len(resSlice) > 0 {
@@ -142,7 +190,7 @@ func (li LineItem) DeleteRelatedLis(id float64, model *Model) {
 
 	for _, rotuer := range model.router.apiFaker.Routers {
 		var isRelatedRouter bool
-		var foreign_key = fmt.Sprintf("%s_id", inflection.Singular(model.Name))
+		var foreign_key = fmt.Sprintf("%s_id", singular(model.Name))
 		for _, column := range rotuer.Model.Columns {
 			if column.Name == foreign_key {
 				isRelatedRouter = true
@@ -151,11 +199,8 @@ func (li LineItem) DeleteRelatedLis(id float64, model *Model) {
 		}
 
 		if isRelatedRouter {
-			for _, li := range rotuer.Model.ToLineItems() {
-				key, ok := li.Get(foreign_key)
-				if ok && key == id {
-					rotuer.Model.Delete(li.ID())
-				}
+			for _, li := range rotuer.Model.FindBy(foreign_key, id) {
+				rotuer.Model.Delete(li.ID())
 			}
 		}
 	}
@@ -177,6 +222,20 @@ func FormatValue(valueType, value string) (interface{}, error) {
 		} else {
 			return booleanVal, nil
 		}
+	case array.Name():
+		arrayVal := []interface{}{}
+		if err := json.Unmarshal([]byte(value), &arrayVal); err != nil {
+			return nilValue, err
+		} else {
+			return arrayVal, nil
+		}
+	case object.Name():
+		objectVal := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(value), &objectVal); err != nil {
+			return nilValue, err
+		} else {
+			return objectVal, nil
+		}
 	default:
 		return value, nil
 	}
@@ -5,10 +5,9 @@ import (
 	"github.com/Focinfi/gset"
 	"github.com/Focinfi/gtester"
 	"github.com/gin-gonic/gin"
-	"github.com/jinzhu/inflection"
 	"io/ioutil"
 	"os"
-	"sort"
+	"path/filepath"
 	"sync"
 )
 
@@ -19,12 +18,52 @@ type Model struct {
 	Seeds   []map[string]interface{} `json:"seeds"`
 	Columns []*Column                `json:"columns"`
 
+	// SeedsFile the csv or ndjson file contains seeds instead of Seeds,
+	// relative path is relative to the directory of the json file
+	SeedsFile string `json:"seeds_file,omitempty"`
+
 	// relationships
 	HasMany []string `json:"has_many"`
 	HasOne  []string `json:"has_one"`
 
-	// Set contains runtime data
-	Set *gset.SetThreadSafe `json:"-"`
+	// StoreType the type of Store, "memory"(default) or "file"
+	StoreType string `json:"store,omitempty"`
+
+	// StoreFile the file of a "file" Store, default "<resource_name>.store",
+	// relative path is relative to the directory of the json file
+	StoreFile string `json:"store_file,omitempty"`
+
+	// Store contains runtime data
+	Store Store `json:"-"`
+
+	// Latency the delay before handling requests of this resource,
+	// ApiFaker.SetLatency overrides it at runtime without changing it
+	Latency *Latency `json:"latency,omitempty"`
+
+	// Faults the errors injected into responses of this resource,
+	// ApiFaker.SetFaults overrides them at runtime without changing them
+	Faults []*Fault `json:"faults,omitempty"`
+
+	// RateLimits the token bucket limits of the requests of every client to this resource
+	RateLimits []*RateLimit `json:"rate_limits,omitempty"`
+
+	// SoftDelete sets deleted_at instead of deleting the LineItem and its related data,
+	// the soft deleted LineItems are hidden unless with_deleted=true is passed
+	SoftDelete bool `json:"soft_delete,omitempty"`
+
+	// Transform shapes the responses of this resource
+	Transform *Transform `json:"transform,omitempty"`
+
+	// OwnerColumn the foreign key column refers to Auth.UserResource, like "user_id",
+	// the LineItems are scoped to the authenticated user, see OwnerMiddleware
+	OwnerColumn string `json:"owner_column,omitempty"`
+
+	// Permissions maps roles to their allowed methods and columns, "*" for the unlisted roles,
+	// all roles can do everything if it is nil, see PermissionMiddleware
+	Permissions map[string]*Permission `json:"permissions,omitempty"`
+
+	// CustomRoutes the endpoints which are not CRUD
+	CustomRoutes []*CustomRoute `json:"routes,omitempty"`
 
 	// currentId records the max of id
 	currentId float64
@@ -33,6 +72,17 @@ type Model struct {
 	dataChanged bool
 	sync.RWMutex
 	router *Router
+
+	// indexes contains Index of foreign key columns and unique columns
+	indexes     map[string]*Index
+	indexesLock sync.Mutex
+
+	// runtimeLatency and runtimeFaults are set by ApiFaker.SetLatency and ApiFaker.SetFaults,
+	// they override Latency and Faults once set and are never saved into the json file
+	runtimeLatency    *Latency
+	runtimeLatencySet bool
+	runtimeFaults     []*Fault
+	runtimeFaultsSet  bool
 }
 
 //------Model CURD------//
@@ -41,7 +91,7 @@ func NewModel(router *Router) *Model {
 	return &Model{
 		Seeds:   []map[string]interface{}{},
 		Columns: []*Column{},
-		Set:     gset.NewSetThreadSafe(),
+		Store:   NewMemoryStore(),
 		router:  router,
 	}
 }
@@ -59,17 +109,35 @@ func NewModelWithPath(path string, router *Router) (*Model, error) {
 	model := NewModel(router)
 	bytes := []byte{}
 
-	err = gtester.NewInspector().
-		Check(func() error { bytes, err = ioutil.ReadAll(file); return err }).
-		Check(func() error { return json.Unmarshal(bytes, model) }).
+	err = gtester.NewCheckQueue().
+		Add(func() error { bytes, err = ioutil.ReadAll(file); return err }).
+		Add(func() error { return json.Unmarshal(bytes, model) }).
+		Add(func() error { return model.setUp(filepath.Dir(path)) }).
+		Run()
+
+	return model, err
+}
+
+// setUp checks the meta and seeds of the Model, then opens its Store and adds the seeds into it,
+// relative paths of SeedsFile and StoreFile are relative to the given dir
+func (model *Model) setUp(dir string) error {
+	return gtester.NewInspector().
+		Check(func() error { return model.loadSeedsFile(dir) }).
 		Check(model.CheckRelationshipsMeta).
 		Check(model.CheckColumnsMeta).
+		Check(model.CheckTransformMeta).
+		Check(model.fillSeedsAutoValues).
+		Check(model.CheckLatencyMeta).
+		Check(model.CheckFaultsMeta).
+		Check(model.CheckRateLimitsMeta).
+		Check(model.CheckRoutesMeta).
+		Check(model.CheckOwnerMeta).
+		Check(model.CheckPermissionsMeta).
 		Check(model.ValidateSeedsValue).
+		Check(func() error { return model.openStore(dir) }).
 		Then(func() {
 			model.initSet()
 		})
-
-	return model, err
 }
 
 // updateId updates currentId if the given id is bigger
@@ -85,23 +153,20 @@ func (model *Model) nextId() float64 {
 	return model.currentId
 }
 
-// Len returns the length of Model's Set
+// Len returns the length of Model's Store
 func (model *Model) Len() int {
-	return model.Set.Len()
+	return model.Store.Len()
 }
 
 // Has returns if Model has LineItem with the given id
 func (model *Model) Has(id float64) bool {
-	return model.Set.Has(gset.T(id))
+	_, ok := model.Store.Get(id)
+	return ok
 }
 
 // Get gets and returns element with id param and the existence of it
-func (model *Model) Get(id float64) (li LineItem, ok bool) {
-	var element interface{}
-	if element, ok = model.Set.Get(id); ok {
-		li, ok = element.(LineItem)
-	}
-	return
+func (model *Model) Get(id float64) (LineItem, bool) {
+	return model.Store.Get(id)
 }
 
 // Add add a LineItem to Model.Set
@@ -113,13 +178,16 @@ func (model *Model) Add(li LineItem) error {
 	if _, ok := li.Get("id"); !ok {
 		li.Set("id", model.nextId())
 	}
+	model.setAutoValues(li, nil)
 
 	if err := model.Validate(li.ToMap()); err != nil {
 		return err
+	} else if err := model.Store.Insert(li); err != nil {
+		return err
 	} else {
-		model.Set.Add(li)
 		model.dataChanged = true
 		model.addUniqueValues(li)
+		model.addIndexes(li)
 	}
 
 	return nil
@@ -127,81 +195,155 @@ func (model *Model) Add(li LineItem) error {
 
 // Update updates the LineItem with the given id by the given LineItem
 func (model *Model) Update(id float64, li *LineItem) error {
+	return model.update(id, li, nil)
+}
+
+// update updates the LineItem with the given id by the given LineItem
+// if precondition is nil or returns no error for the LineItem under the write lock
+func (model *Model) update(id float64, li *LineItem, precondition func(li LineItem) error) error {
+	model.Lock()
+	defer model.Unlock()
+
 	oldLi, ok := model.Get(id)
 	if !ok {
 		return SeedsErrorf("model %s[id:%d] does not exsit", model.Name, id)
 	}
-
-	model.Lock()
-	defer model.Unlock()
+	if precondition != nil {
+		if err := precondition(oldLi); err != nil {
+			return err
+		}
+	}
 
 	// set id if the given LineItem has no id
 	if _, ok := li.Get("id"); !ok {
 		li.Set("id", id)
 	}
+	model.setAutoValues(*li, &oldLi)
 
 	if err := model.Validate(li.dataMap); err != nil {
 		return err
+	} else if err := model.Store.Update(*li); err != nil {
+		return err
 	} else {
-		model.Set.Add(*li)
 		model.dataChanged = true
 		model.removeUniqueValues(oldLi)
 		model.addUniqueValues(*li)
+		model.removeIndexes(oldLi)
+		model.addIndexes(*li)
 	}
 
 	return nil
 }
 
-// Delete deletes the LineItem and its related data with the given id
+// Delete deletes the LineItem and its related data with the given id,
+// only sets deleted_at of the LineItem if SoftDelete is true
 func (model *Model) Delete(id float64) {
+	model.delete(id, nil)
+}
+
+// delete deletes the LineItem like Delete if precondition is nil or returns no error for the LineItem under the write lock
+func (model *Model) delete(id float64, precondition func(li LineItem) error) error {
 	model.Lock()
 	defer model.Unlock()
 
 	li, ok := model.Get(id)
 
 	if !ok {
-		return
+		return nil
+	}
+	if precondition != nil {
+		if err := precondition(li); err != nil {
+			return err
+		}
+	}
+
+	if model.SoftDelete {
+		if !li.isDeleted() {
+			model.softDelete(li)
+		}
+		return nil
 	}
 
 	li.DeleteRelatedLis(id, model)
-	model.Set.Remove(gset.T(id))
+	model.Store.Delete(id)
 	model.dataChanged = true
 	model.removeUniqueValues(li)
+	model.removeIndexes(li)
+	return nil
 }
 
 // UpdateWithAttrsInGinContext finds a LineItem with id param,
-// updates it with attrs from gin.Contex.PostForm(),
-// returns the edited LineItem
+// updates it with attrs from gin.Contex.PostForm() named as columns or the keys renamed by Transform,
+// returns the edited LineItem, or errPreconditionFailed if the If-Match header does not match it
 func (model *Model) UpdateWithAttrs(id float64, ctx *gin.Context) (LineItem, error) {
+	return model.updateWith(id, model.ifMatch(ctx), func(column *Column) (interface{}, bool, error) {
+		value := model.formValue(ctx, column.Name)
+		if value == "" {
+			return nil, false, nil
+		}
+
+		formatVal, err := FormatValue(column.Type, value)
+		return formatVal, true, err
+	})
+}
+
+// updateWith finds a LineItem with id param, updates every column with the value got by valueOf,
+// skips id, auto columns and the columns valueOf has no value for, returns the edited LineItem,
+// it updates nothing if precondition is not nil and returns error for the LineItem under the write lock
+func (model *Model) updateWith(id float64, precondition func(li LineItem) error, valueOf func(column *Column) (interface{}, bool, error)) (LineItem, error) {
+	model.Lock()
+	defer model.Unlock()
+
 	// check if element does exsit
 	li, ok := model.Get(id)
 	if !ok {
 		return li, SeedsErrorf("model %s[id:%d] does not exsit", model.Name, id)
 	}
+	if precondition != nil {
+		if err := precondition(li); err != nil {
+			return li, err
+		}
+	}
 
-	// update model
-	for _, column := range model.Columns {
-		value := ctx.PostForm(column.Name)
+	// update a copy of LineItem and write it back into Store only if every value is valid,
+	// the uniqueness of the copy is replaced by the one of the LineItem on errors
+	newLi := NewLineItemWithMap(li.ToMap())
+	rollback := func(err error) (LineItem, error) {
+		model.removeUniqueValues(newLi)
+		model.addUniqueValues(li)
+		return li, err
+	}
 
-		if value == "" || column.Name == "id" {
+	for _, column := range model.Columns {
+		if column.Name == "id" || column.isAuto() {
 			continue
 		}
 
-		formatVal, err := FormatValue(column.Type, value)
+		formatVal, ok, err := valueOf(column)
+		if !ok && err == nil {
+			continue
+		}
 		if err == nil {
 			err = column.CheckValue(formatVal, model)
 		}
-
 		if err != nil {
-			return li, err
-		} else {
-			oldValue, _ := li.Get(column.Name)
-			column.RemoveUniquenessOf(oldValue)
-			li.Set(column.Name, formatVal)
-			column.AddUniquenessOf(formatVal)
+			return rollback(err)
 		}
+
+		oldValue, _ := newLi.Get(column.Name)
+		column.RemoveUniquenessOf(oldValue)
+		newLi.Set(column.Name, formatVal)
+		column.AddUniquenessOf(formatVal)
+	}
+	model.setAutoValues(newLi, &li)
+
+	if err := model.Store.Update(newLi); err != nil {
+		return rollback(err)
 	}
-	return li, nil
+	model.dataChanged = true
+	model.removeIndexes(li)
+	model.addIndexes(newLi)
+	return newLi, nil
 }
 
 //------End Model CURD------//
@@ -271,17 +413,45 @@ func (model *Model) CheckColumnsMeta() error {
 	return nil
 }
 
+// CheckLatencyMeta checks Latency if it is present
+func (model *Model) CheckLatencyMeta() error {
+	if model.Latency == nil {
+		return nil
+	}
+	return model.Latency.CheckMeta()
+}
+
+// CheckFaultsMeta checks every Fault
+func (model *Model) CheckFaultsMeta() error {
+	for _, fault := range model.Faults {
+		if err := fault.CheckMeta(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckRoutesMeta checks every CustomRoute
+func (model *Model) CheckRoutesMeta() error {
+	for _, route := range model.CustomRoutes {
+		if err := route.CheckMeta(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // CheckRelationship
 //   1. checks if every resource in HasOne and HasMany exists
 //   2. CheckRelationships
 func (model *Model) CheckRelationship(seed map[string]interface{}) error {
 	for _, resoureName := range model.HasOne {
-		if _, ok := model.router.apiFaker.Routers[inflection.Plural(resoureName)]; !ok {
+		if _, ok := model.router.apiFaker.Routers[plural(resoureName)]; !ok {
 			return HasOneErrorf("use unknown reource %s in file: %s", resoureName, model.router.filePath)
 		}
 	}
 	for _, resoureName := range model.HasMany {
-		if _, ok := model.router.apiFaker.Routers[inflection.Plural(resoureName)]; !ok {
+		if _, ok := model.router.apiFaker.Routers[plural(resoureName)]; !ok {
 			return HasManyErrorf("use unknown reource \"%s\" in file: %s", resoureName, model.router.filePath)
 		}
 	}
@@ -309,7 +479,15 @@ func (model *Model) CheckRelationships() error {
 func (model *Model) ValidateValue(seed map[string]interface{}) error {
 	columns := model.Columns
 
-	if len(seed) != len(columns) {
+	if err := model.checkDeletedAt(seed); err != nil {
+		return err
+	}
+
+	size := len(seed)
+	if _, ok := seed[deletedAtKey]; ok && !model.hasColumn(deletedAtKey) {
+		size--
+	}
+	if size != len(columns) {
 		return SeedsErrorf("has wrong number of columns: %v", seed)
 	}
 
@@ -355,7 +533,7 @@ func (model *Model) CheckUniqueness() error {
 
 	// check other unique columns
 	for _, column := range model.Columns {
-		if column.Unique && column.uniqueValues.Len() != model.Len() {
+		if column.Unique && column.getUniqueValues().Len() != model.Len() {
 			return SeedsErrorf("column[name=\"%s\"] in model[name=\"%s\"] has same values", column.Name, model.Name)
 		}
 	}
@@ -366,27 +544,69 @@ func (model *Model) CheckUniqueness() error {
 //------End Check------//
 
 //------Seeds and Set------//
-// initSet adds all LineItem into Set, addUniqueValues and updateId
+// openStore opens the Store described by StoreType and StoreFile,
+// the file of a "file" Store is never written if the ApiFaker is InMemory
+func (model *Model) openStore(dir string) error {
+	switch model.StoreType {
+	case "", memoryStore:
+		if model.Store == nil {
+			model.Store = NewMemoryStore()
+		}
+	case fileStore:
+		path := model.StoreFile
+		if path == "" {
+			path = model.Name + ".store"
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+
+		open := NewFileStore
+		if model.router != nil && model.router.apiFaker != nil && model.router.apiFaker.InMemory {
+			open = NewReadOnlyFileStore
+		}
+		store, err := open(path)
+		if err != nil {
+			return err
+		}
+		model.Store = store
+	default:
+		return JsonFileErrorf("model[name=\"%s\"] use unknown store: %s", model.Name, model.StoreType)
+	}
+
+	return nil
+}
+
+// initSet adds all LineItem into Store, addUniqueValues and updateId,
+// the seeds will be skipped if the Store has restored data from its file
 func (model *Model) initSet() {
-	if model.Set == nil {
-		model.Set = gset.NewSetThreadSafe()
+	if model.Store == nil {
+		model.Store = NewMemoryStore()
 	}
+
+	if store, ok := model.Store.(*FileStore); ok && store.Existed() {
+		lis := model.Store.List()
+		model.addUniqueValues(lis...)
+		model.addIndexes(lis...)
+		for _, li := range lis {
+			model.updateId(li.ID())
+		}
+		model.dataChanged = true
+		return
+	}
+
 	for _, seed := range model.Seeds {
 		li := NewLineItemWithMap(seed)
-		model.Set.Add(li)
+		model.Store.Insert(li)
 		model.addUniqueValues(li)
+		model.addIndexes(li)
 		model.updateId(li.ID())
 	}
 }
 
-// backfillSeeds
+// backfillSeeds, the caller must hold the write lock of Model
 func (model *Model) backfillSeeds() {
-	model.RLock()
-	defer model.RUnlock()
-
-	models := model.ToLineItems()
-	sort.Sort(models)
-	model.Seeds = models.ToSlice()
+	model.Seeds = model.lineItems().ToSlice()
 	model.dataChanged = false
 }
 
@@ -395,31 +615,47 @@ func (model *Model) backfillSeeds() {
 // ToLineItems allocate a new LineItems filled with Model elements slice
 func (model *Model) ToLineItems() LineItems {
 	lis := []LineItem{}
-	elements := model.Set.ToSlice()
-	for _, element := range elements {
-		if li, ok := element.(LineItem); ok {
-			newLi := li.InsertRelatedData(model)
-			lis = append(lis[:], newLi)
-		}
+	for _, li := range model.Store.List() {
+		newLi := li.InsertRelatedData(model)
+		lis = append(lis[:], newLi)
 	}
 	return LineItems(lis)
 }
 
-// SaveToFile save model to file with the given path
+// lineItems returns all LineItems of Model sorted by id, without related data
+func (model *Model) lineItems() LineItems {
+	return model.Store.List()
+}
+
+// SaveToFile save model to file with the given path,
+// if Store is a "file" Store, only the Store will be snapshotted,
+// if SeedsFile is present, only the seeds file will be rewritten
 func (model *Model) SaveToFile(path string) error {
-	file, err := os.Create(path)
-	if err != nil {
-		return err
+	if model.StoreType == fileStore {
+		return model.Store.Snapshot()
+	}
+
+	if model.SeedsFile != "" {
+		model.RLock()
+		defer model.RUnlock()
+		return model.saveSeedsFile(filepath.Dir(path))
 	}
-	defer file.Close()
 
+	model.Lock()
 	if model.dataChanged {
 		model.backfillSeeds()
 	}
 	bytes, err := json.Marshal(model)
+	model.Unlock()
 	if err != nil {
 		return err
 	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
 	_, err = file.WriteString(string(bytes))
 
 	return err
@@ -1,8 +1,12 @@
 package apifaker
 
 import (
+	"bytes"
+	"fmt"
 	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -214,4 +218,174 @@ func TestModel(t *testing.T) {
 			Expect(err, ShouldBeNil)
 		})
 	})
+
+	Describ("SeedsFile", t, func() {
+		dir, _ := ioutil.TempDir("", "apifaker")
+		defer os.RemoveAll(dir)
+		schema := `{"resource_name": "tags", "seeds_file": "%s", "columns": [
+			{"name": "id", "type": "number"},
+			{"name": "name", "type": "string", "unique": true},
+			{"name": "aliases", "type": "array"}]}`
+
+		Context("when seeds_file is a csv file", func() {
+			ioutil.WriteFile(filepath.Join(dir, "tags.json"), []byte(fmt.Sprintf(schema, "tags.csv")), 0644)
+			ioutil.WriteFile(filepath.Join(dir, "tags.csv"), []byte("id,name,aliases\n1,go,\"[\"\"golang\"\"]\"\n2,rust,[]\n"), 0644)
+			model, err := NewModelWithPath(filepath.Join(dir, "tags.json"), testRouter)
+			It("loads seeds from the csv file", func() {
+				Expect(err, ShouldBeNil)
+				Expect(model.Len(), ShouldEqual, 2)
+				li, _ := model.Get(float64(1))
+				aliases, _ := li.Get("aliases")
+				Expect(aliases, ShouldResemble, []interface{}{"golang"})
+			})
+
+			model.Add(NewLineItemWithMap(map[string]interface{}{"name": "c", "aliases": []interface{}{}}))
+			err = model.SaveToFile(filepath.Join(dir, "tags.json"))
+			csvBytes, _ := ioutil.ReadFile(filepath.Join(dir, "tags.csv"))
+			jsonBytes, _ := ioutil.ReadFile(filepath.Join(dir, "tags.json"))
+			It("saves seeds back to the csv file and leaves the json file", func() {
+				Expect(err, ShouldBeNil)
+				Expect(string(csvBytes), ShouldEqual, "id,name,aliases\n1,go,\"[\"\"golang\"\"]\"\n2,rust,[]\n3,c,[]\n")
+				Expect(string(jsonBytes), ShouldEqual, fmt.Sprintf(schema, "tags.csv"))
+			})
+		})
+
+		Context("when a csv file is saved with null values", func() {
+			model := NewModel(testRouter)
+			model.Columns = []*Column{{Name: "id", Type: "number"}, {Name: "age", Type: "number"}, {Name: "aliases", Type: "array"}}
+			model.SeedsFile = "tags.csv"
+			buffer := &bytes.Buffer{}
+			err := model.writeCSVSeeds(buffer, LineItems{NewLineItemWithMap(map[string]interface{}{"id": float64(1), "age": nil, "aliases": nil})})
+
+			It("reads the null values back", func() {
+				Expect(err, ShouldBeNil)
+				Expect(model.readCSVSeeds(buffer), ShouldBeNil)
+				Expect(model.Seeds, ShouldResemble, []map[string]interface{}{{"id": float64(1), "age": nil, "aliases": nil}})
+			})
+		})
+
+		Context("when seeds_file is a ndjson file", func() {
+			ioutil.WriteFile(filepath.Join(dir, "tags.json"), []byte(fmt.Sprintf(schema, "tags.ndjson")), 0644)
+			ioutil.WriteFile(filepath.Join(dir, "tags.ndjson"), []byte(`{"id":1,"name":"go","aliases":["golang"]}`+"\n"), 0644)
+			model, err := NewModelWithPath(filepath.Join(dir, "tags.json"), testRouter)
+			It("loads seeds from the ndjson file", func() {
+				Expect(err, ShouldBeNil)
+				Expect(model.Len(), ShouldEqual, 1)
+			})
+
+			model.Add(NewLineItemWithMap(map[string]interface{}{"name": "c", "aliases": []interface{}{}}))
+			err = model.SaveToFile(filepath.Join(dir, "tags.json"))
+			bytes, _ := ioutil.ReadFile(filepath.Join(dir, "tags.ndjson"))
+			It("saves seeds back to the ndjson file", func() {
+				Expect(err, ShouldBeNil)
+				Expect(string(bytes), ShouldEqual, `{"aliases":["golang"],"id":1,"name":"go"}`+"\n"+`{"aliases":[],"id":2,"name":"c"}`+"\n")
+			})
+		})
+
+		Context("when has both seeds and seeds_file", func() {
+			ioutil.WriteFile(filepath.Join(dir, "tags.json"), []byte(`{"resource_name": "tags", "seeds_file": "tags.ndjson",
+				"columns": [{"name": "id", "type": "number"}], "seeds": [{"id": 1}]}`), 0644)
+			_, err := NewModelWithPath(filepath.Join(dir, "tags.json"), testRouter)
+			It("returns error", func() {
+				Expect(err, ShouldNotBeNil)
+			})
+		})
+	})
+
+	Describ("FileStore", t, func() {
+		dir, _ := ioutil.TempDir("", "apifaker")
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "tags.json")
+		ioutil.WriteFile(path, []byte(`{"resource_name": "tags", "store": "file",
+			"columns": [{"name": "id", "type": "number"}, {"name": "name", "type": "string"}],
+			"seeds": [{"id": 1, "name": "go"}, {"id": 2, "name": "rust"}]}`), 0644)
+
+		model, err := NewModelWithPath(path, testRouter)
+		It("initializes the store file with seeds", func() {
+			Expect(err, ShouldBeNil)
+			Expect(model.Len(), ShouldEqual, 2)
+		})
+
+		model.Add(NewLineItemWithMap(map[string]interface{}{"name": "c"}))
+		model.Delete(float64(1))
+		model.Store.Close()
+		bytes, _ := ioutil.ReadFile(filepath.Join(dir, "tags.store"))
+		It("appends every change into the store file", func() {
+			Expect(string(bytes), ShouldEqual, `{"op":"put","item":{"id":1,"name":"go"}}`+"\n"+
+				`{"op":"put","item":{"id":2,"name":"rust"}}`+"\n"+
+				`{"op":"put","item":{"id":3,"name":"c"}}`+"\n"+
+				`{"op":"delete","id":1}`+"\n")
+		})
+
+		model, err = NewModelWithPath(path, testRouter)
+		It("restores data from the store file instead of seeds", func() {
+			Expect(err, ShouldBeNil)
+			Expect(model.Len(), ShouldEqual, 2)
+			Expect(model.Has(float64(1)), ShouldBeFalse)
+			Expect(model.Has(float64(3)), ShouldBeTrue)
+			Expect(model.currentId, ShouldEqual, 3)
+		})
+
+		err = model.SaveToFile(path)
+		model.Store.Close()
+		bytes, _ = ioutil.ReadFile(filepath.Join(dir, "tags.store"))
+		It("compacts the store file when saving", func() {
+			Expect(err, ShouldBeNil)
+			Expect(string(bytes), ShouldEqual, `{"op":"put","item":{"id":2,"name":"rust"}}`+"\n"+
+				`{"op":"put","item":{"id":3,"name":"c"}}`+"\n")
+		})
+
+		storeBytes, _ := ioutil.ReadFile(filepath.Join(dir, "tags.store"))
+		model, err = NewModelWithPath(path, &Router{apiFaker: &ApiFaker{InMemory: true}})
+		addErr := model.Add(NewLineItemWithMap(map[string]interface{}{"name": "zig"}))
+		model.Store.Close()
+		bytes, _ = ioutil.ReadFile(filepath.Join(dir, "tags.store"))
+		It("restores data but never writes the store file if the ApiFaker is InMemory", func() {
+			Expect(err, ShouldBeNil)
+			Expect(addErr, ShouldBeNil)
+			Expect(model.Len(), ShouldEqual, 3)
+			Expect(string(bytes), ShouldEqual, string(storeBytes))
+
+			_, err := NewReadOnlyFileStore(filepath.Join(dir, "missing.store"))
+			_, statErr := os.Stat(filepath.Join(dir, "missing.store"))
+			Expect(err, ShouldBeNil)
+			Expect(os.IsNotExist(statErr), ShouldBeTrue)
+		})
+
+		store, _ := NewFileStore(filepath.Join(dir, "tags.store"))
+		os.Mkdir(filepath.Join(dir, "tags.store.tmp"), 0755)
+		snapshotErr := store.Snapshot()
+		insertErr := store.Insert(NewLineItemWithMap(map[string]interface{}{"id": float64(4), "name": "zig"}))
+		store.Close()
+		bytes, _ = ioutil.ReadFile(filepath.Join(dir, "tags.store"))
+		It("keeps appending into the old file if the snapshot fails", func() {
+			Expect(snapshotErr, ShouldNotBeNil)
+			Expect(insertErr, ShouldBeNil)
+			Expect(string(bytes), ShouldEndWith, `{"op":"put","item":{"id":4,"name":"zig"}}`+"\n")
+		})
+
+		racePath := filepath.Join(dir, "race.store")
+		store, _ = NewFileStore(racePath)
+		done := make(chan struct{})
+		go func() {
+			for i := 1; i <= 200; i++ {
+				store.Insert(NewLineItemWithMap(map[string]interface{}{"id": float64(i)}))
+			}
+			close(done)
+		}()
+		for running := true; running; {
+			select {
+			case <-done:
+				running = false
+			default:
+				store.Snapshot()
+			}
+		}
+		store.Close()
+		reopened, _ := NewFileStore(racePath)
+		It("never loses the changes made during a snapshot", func() {
+			Expect(reopened.Len(), ShouldEqual, 200)
+		})
+		reopened.Close()
+	})
 }
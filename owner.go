@@ -0,0 +1,122 @@
+package apifaker
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CheckOwnerMeta checks OwnerColumn if it is present,
+// it must be a number column named like "user_id" which refers to a resource
+func (model *Model) CheckOwnerMeta() error {
+	if model.OwnerColumn == "" {
+		return nil
+	}
+
+	for _, column := range model.Columns {
+		if column.Name != model.OwnerColumn {
+			continue
+		}
+		if column.Type != number.Name() || !strings.HasSuffix(column.Name, "_id") || column.isAuto() {
+			return ColumnsErrorf("owner_column %s of model %s must be a number column named like user_id", column.Name, model.Name)
+		}
+		return nil
+	}
+	return ColumnsErrorf("model %s has no owner_column %s", model.Name, model.OwnerColumn)
+}
+
+// ownerResource returns the name of the resource OwnerColumn refers to, like "users" for "user_id"
+func (model *Model) ownerResource() string {
+	return plural(strings.TrimSuffix(model.OwnerColumn, "_id"))
+}
+
+// ownerIdOf returns the id of the user who sends the request,
+// false if the Model has no OwnerColumn or the caller is not a user of Auth.UserResource, like an api key
+func (model *Model) ownerIdOf(ctx *gin.Context) (float64, bool) {
+	if model.OwnerColumn == "" {
+		return 0, false
+	}
+
+	identity, ok := IdentityOf(ctx)
+	if !ok || identity.User == nil {
+		return 0, false
+	}
+	id, ok := identity.User["id"].(float64)
+	return id, ok
+}
+
+// isOwnedBy returns if the LineItem belongs to the user with the given id
+func (model *Model) isOwnedBy(li LineItem, ownerId float64) bool {
+	value, _ := li.Get(model.OwnerColumn)
+	return value == ownerId
+}
+
+// ownedOnly returns the LineItems belong to the caller, or all LineItems if the caller is not scoped
+func (model *Model) ownedOnly(ctx *gin.Context, lis LineItems) LineItems {
+	ownerId, ok := model.ownerIdOf(ctx)
+	if !ok {
+		return lis
+	}
+
+	owned := LineItems{}
+	for _, li := range lis {
+		if model.isOwnedBy(li, ownerId) {
+			owned = append(owned, li)
+		}
+	}
+	return owned
+}
+
+// OwnerMiddleware returns a gin.HandlerFunc which scopes the resources with OwnerColumn to the authenticated user,
+// it must be used after the id check middleware:
+//  1. GET /collection/:id of another user's item responses 404
+//  2. PUT, PATCH, DELETE and restore of another user's item response 403
+//  3. POST and PUT fill OwnerColumn with the id of the user, and response 403 if it is another user's id
+//
+// requests without a user, like public ones or ones with api keys, are not scoped
+func OwnerMiddleware(faker *ApiFaker) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		router, ok := faker.routerOfPath(ctx.Request.URL.Path)
+		if !ok {
+			return
+		}
+		model := router.Model
+		ownerId, ok := model.ownerIdOf(ctx)
+		if !ok {
+			return
+		}
+
+		if id, ok := ctx.Get("idFloat64"); ok {
+			li, _ := model.Get(id.(float64))
+			if !model.isOwnedBy(li, ownerId) {
+				if ctx.Request.Method == http.MethodGet {
+					ctx.JSON(http.StatusNotFound, nil)
+				} else {
+					ctx.JSON(http.StatusForbidden, map[string]string{"message": "the item belongs to another user"})
+				}
+				ctx.Abort()
+				return
+			}
+		}
+
+		if ctx.Request.Method != http.MethodPost && ctx.Request.Method != http.MethodPut && ctx.Request.Method != http.MethodPatch {
+			return
+		}
+
+		owner := fmt.Sprint(ownerId)
+		if value := model.formValue(ctx, model.OwnerColumn); value != "" {
+			if formatVal, err := FormatValue(number.Name(), value); err != nil || formatVal != ownerId {
+				ctx.JSON(http.StatusForbidden, map[string]string{"message": fmt.Sprintf("%s must be %s", model.OwnerColumn, owner)})
+				ctx.Abort()
+			}
+			return
+		}
+
+		if ctx.Request.Method != http.MethodPatch {
+			ctx.Request.ParseForm()
+			ctx.Request.PostForm.Set(model.OwnerColumn, owner)
+		}
+	}
+}
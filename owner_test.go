@@ -0,0 +1,81 @@
+package apifaker
+
+import (
+	"encoding/json"
+	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOwner(t *testing.T) {
+	dir := newAuthTestDir()
+	defer os.RemoveAll(dir)
+	bytes, _ := ioutil.ReadFile(filepath.Join(dir, "posts.json"))
+	ioutil.WriteFile(filepath.Join(dir, "posts.json"),
+		[]byte(strings.Replace(string(bytes), `"resource_name": "posts",`, `"resource_name": "posts", "owner_column": "account_id",`, 1)), 0644)
+
+	faker, err := NewWithApiDir(dir)
+	faker.InMemory = true
+	faker.SetAuth(&Auth{
+		ApiKeys:      map[string]*Identity{"key-1": {Subject: "ci"}},
+		Basic:        true,
+		UserResource: "accounts",
+	})
+	request := func(method, path string, form url.Values, username, password string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if username != "" {
+			req.SetBasicAuth(username, password)
+		} else {
+			req.Header.Set("X-Api-Key", "key-1")
+		}
+		rw := httptest.NewRecorder()
+		faker.ServeHTTP(rw, req)
+		return rw
+	}
+
+	Describ("CheckOwnerMeta", t, func() {
+		It("checks the owner column", func() {
+			Expect(err, ShouldBeNil)
+			Expect((&Model{Name: "posts", OwnerColumn: "title", Columns: []*Column{{Name: "title", Type: "string"}}}).CheckOwnerMeta(), ShouldNotBeNil)
+			Expect((&Model{Name: "posts", OwnerColumn: "user_id"}).CheckOwnerMeta(), ShouldNotBeNil)
+			Expect(faker.SetAuth(&Auth{UserResource: "users"}), ShouldNotBeNil)
+		})
+	})
+
+	Describ("OwnerMiddleware", t, func() {
+		It("scopes the collection to the user", func() {
+			posts := []map[string]interface{}{}
+			json.Unmarshal(request("GET", "/posts", nil, "tony", "jarvis").Body.Bytes(), &posts)
+			Expect(len(posts), ShouldEqual, 1)
+			Expect(posts[0]["account_id"], ShouldEqual, 2)
+
+			json.Unmarshal(request("GET", "/posts", nil, "", "").Body.Bytes(), &posts)
+			Expect(len(posts), ShouldEqual, 2)
+		})
+
+		It("hides the items of other users", func() {
+			Expect(request("GET", "/posts/2", nil, "tony", "jarvis").Code, ShouldEqual, http.StatusOK)
+			Expect(request("GET", "/posts/1", nil, "tony", "jarvis").Code, ShouldEqual, http.StatusNotFound)
+			Expect(request("PATCH", "/posts/1", url.Values{"title": {"x"}}, "tony", "jarvis").Code, ShouldEqual, http.StatusForbidden)
+			Expect(request("DELETE", "/posts/1", nil, "tony", "jarvis").Code, ShouldEqual, http.StatusForbidden)
+		})
+
+		It("fills the owner", func() {
+			response := request("POST", "/posts", url.Values{"title": {"c"}}, "tony", "jarvis")
+			Expect(response.Code, ShouldEqual, http.StatusOK)
+			post := map[string]interface{}{}
+			json.Unmarshal(response.Body.Bytes(), &post)
+			Expect(post["account_id"], ShouldEqual, 2)
+
+			response = request("POST", "/posts", url.Values{"title": {"d"}, "account_id": {"1"}}, "tony", "jarvis")
+			Expect(response.Code, ShouldEqual, http.StatusForbidden)
+		})
+	})
+}
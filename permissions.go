@@ -0,0 +1,188 @@
+package apifaker
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// RoleHeader the header of the role when ApiFaker has no Auth
+	RoleHeader = "X-Apifaker-Role"
+
+	// anyRole the key of Permissions for the roles which are not listed
+	anyRole = "*"
+)
+
+// Permission the methods and columns allowed for a role
+type Permission struct {
+	// Methods the allowed methods, like ["GET", "PATCH"], restoring a soft deleted item is a POST
+	Methods []string `json:"methods"`
+
+	// Read the columns in responses, all columns if it is empty, id is always readable
+	Read []string `json:"read,omitempty"`
+
+	// Write the columns can be set by POST, PUT and PATCH, all columns if it is empty
+	Write []string `json:"write,omitempty"`
+
+	methods RestMethod
+}
+
+// CheckMeta checks every method and sets the methods mask
+func (permission *Permission) CheckMeta() error {
+	permission.methods = 0
+	for _, name := range permission.Methods {
+		method, err := ParseRestMethod(name)
+		if err != nil {
+			return PermissionsErrorf("%v", err)
+		}
+		permission.methods |= method
+	}
+	return nil
+}
+
+// allows returns if the http method is allowed
+func (permission *Permission) allows(name string) bool {
+	method, err := ParseRestMethod(name)
+	return err == nil && permission.methods&method != 0
+}
+
+// canRead returns if the column can be in responses
+func (permission *Permission) canRead(column string) bool {
+	return column == "id" || len(permission.Read) == 0 || containsString(permission.Read, column)
+}
+
+// canWrite returns if the column can be set
+func (permission *Permission) canWrite(column string) bool {
+	return len(permission.Write) == 0 || containsString(permission.Write, column)
+}
+
+// CheckPermissionsMeta checks every Permission, the columns of Read and Write must exist
+func (model *Model) CheckPermissionsMeta() error {
+	for role, permission := range model.Permissions {
+		if permission == nil {
+			return PermissionsErrorf("permission of role %s in resource %s must not be null", role, model.Name)
+		}
+		if err := permission.CheckMeta(); err != nil {
+			return err
+		}
+		for _, name := range append(append([]string{}, permission.Read...), permission.Write...) {
+			if !model.hasColumn(name) {
+				return PermissionsErrorf("column %s of role %s in resource %s does not exist", name, role, model.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// roleOf returns the role of the authenticated Identity,
+// RoleHeader is only honored if ApiFaker has no Auth, so that clients can not choose their roles
+func (af *ApiFaker) roleOf(ctx *gin.Context) string {
+	if identity, ok := IdentityOf(ctx); ok {
+		return identity.Role
+	}
+	if af.Auth != nil {
+		return ""
+	}
+	return ctx.Request.Header.Get(RoleHeader)
+}
+
+// permissionOf returns the Permission of the role of the request, the one of "*" is used for unlisted roles,
+// returns nil and true if the Model has no Permissions
+func (model *Model) permissionOf(ctx *gin.Context) (*Permission, bool) {
+	if model.Permissions == nil {
+		return nil, true
+	}
+
+	if permission, ok := model.Permissions[model.router.apiFaker.roleOf(ctx)]; ok {
+		return permission, true
+	}
+	permission, ok := model.Permissions[anyRole]
+	return permission, ok
+}
+
+// readable returns a copy of the LineItem without the columns the role of the request can not read
+func (model *Model) readable(ctx *gin.Context, li LineItem) LineItem {
+	permission, _ := model.permissionOf(ctx)
+	if permission == nil || len(permission.Read) == 0 {
+		return li
+	}
+
+	newLi := NewLineItemWithMap(li.ToMap())
+	for _, column := range model.Columns {
+		if !permission.canRead(column.Name) {
+			delete(newLi.dataMap, column.Name)
+		}
+	}
+	return newLi
+}
+
+// readableAll returns the readable copies of the LineItems
+func (model *Model) readableAll(ctx *gin.Context, lis LineItems) LineItems {
+	newLis := LineItems{}
+	for _, li := range lis {
+		newLis = append(newLis, model.readable(ctx, li))
+	}
+	return newLis
+}
+
+// isStoredValue returns if the form value of the column equals the value of the column in the LineItem
+func isStoredValue(li LineItem, column *Column, value string) bool {
+	formatVal, err := FormatValue(column.Type, value)
+	if err != nil {
+		return false
+	}
+	stored, _ := li.Get(column.Name)
+	return reflect.DeepEqual(formatVal, stored)
+}
+
+// PermissionMiddleware returns a gin.HandlerFunc which enforces the Permissions of resources,
+// the role comes from the authenticated Identity, or RoleHeader if ApiFaker has no Auth, it responses 403 if
+//  1. the role has no Permission and there is no Permission of "*"
+//  2. the method is not allowed
+//  3. POST, PUT or PATCH sets a column the role can not write, PUT can send its stored value
+func PermissionMiddleware(faker *ApiFaker) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		router, ok := faker.routerOfPath(ctx.Request.URL.Path)
+		if !ok {
+			return
+		}
+		model := router.Model
+
+		permission, ok := model.permissionOf(ctx)
+		if permission == nil && ok {
+			return
+		}
+
+		message := ""
+		if !ok {
+			message = "role " + faker.roleOf(ctx) + " has no permission"
+		} else if !permission.allows(ctx.Request.Method) {
+			message = "role " + faker.roleOf(ctx) + " can not " + ctx.Request.Method + " " + model.Name
+		} else if ctx.Request.Method != http.MethodGet && ctx.Request.Method != http.MethodDelete {
+			// PUT sends every column, the ones the role can not write must keep their values
+			stored, isPut := LineItem{}, false
+			if ctx.Request.Method == http.MethodPut {
+				if id, err := strconv.ParseFloat(ctx.Param("id"), 64); err == nil {
+					stored, isPut = model.Get(id)
+				}
+			}
+
+			for _, column := range model.Columns {
+				value := model.formValue(ctx, column.Name)
+				if permission.canWrite(column.Name) || value == "" || (isPut && isStoredValue(stored, column, value)) {
+					continue
+				}
+				message = "role " + faker.roleOf(ctx) + " can not write " + column.Name
+				break
+			}
+		}
+
+		if message != "" {
+			ctx.JSON(http.StatusForbidden, map[string]string{"message": message})
+			ctx.Abort()
+		}
+	}
+}
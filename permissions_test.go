@@ -0,0 +1,95 @@
+package apifaker
+
+import (
+	"encoding/json"
+	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPermissions(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "apifaker")
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "books.json"), []byte(`{"resource_name": "books",
+		"columns": [{"name": "id", "type": "number"}, {"name": "title", "type": "string"}, {"name": "price", "type": "number"}],
+		"seeds": [{"id": 1, "title": "a", "price": 10}],
+		"permissions": {
+			"admin": {"methods": ["GET", "POST", "PUT", "PATCH", "DELETE"]},
+			"editor": {"methods": ["GET", "PUT", "PATCH"], "write": ["title"]},
+			"*": {"methods": ["GET"], "read": ["title"]}
+		}}`), 0644)
+
+	faker, err := NewWithApiDir(dir)
+	faker.InMemory = true
+	request := func(method, path string, form url.Values, role string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set(RoleHeader, role)
+		rw := httptest.NewRecorder()
+		faker.ServeHTTP(rw, req)
+		return rw
+	}
+
+	Describ("CheckPermissionsMeta", t, func() {
+		It("checks the methods and columns", func() {
+			Expect(err, ShouldBeNil)
+			model := &Model{Name: "books", Columns: []*Column{{Name: "id", Type: "number"}}}
+			model.Permissions = map[string]*Permission{"admin": {Methods: []string{"FETCH"}}}
+			Expect(model.CheckPermissionsMeta(), ShouldNotBeNil)
+			model.Permissions = map[string]*Permission{"admin": {Methods: []string{"GET"}, Read: []string{"title"}}}
+			Expect(model.CheckPermissionsMeta(), ShouldNotBeNil)
+		})
+	})
+
+	Describ("PermissionMiddleware", t, func() {
+		It("allows the methods of the role", func() {
+			Expect(request("DELETE", "/books/1", nil, "editor").Code, ShouldEqual, http.StatusForbidden)
+			Expect(request("PATCH", "/books/1", url.Values{"title": {"b"}}, "editor").Code, ShouldEqual, http.StatusOK)
+			Expect(request("POST", "/books", url.Values{"title": {"c"}, "price": {"1"}}, "viewer").Code, ShouldEqual, http.StatusForbidden)
+		})
+
+		It("allows the writable columns", func() {
+			Expect(request("PATCH", "/books/1", url.Values{"price": {"20"}}, "editor").Code, ShouldEqual, http.StatusForbidden)
+		})
+
+		It("allows PUT to send the stored values of the columns the role can not write", func() {
+			Expect(request("PUT", "/books/1", url.Values{"title": {"d"}, "price": {"10"}}, "editor").Code, ShouldEqual, http.StatusOK)
+			Expect(request("PUT", "/books/1", url.Values{"title": {"e"}, "price": {"20"}}, "editor").Code, ShouldEqual, http.StatusForbidden)
+		})
+
+		It("hides the unreadable columns", func() {
+			book := map[string]interface{}{}
+			json.Unmarshal(request("GET", "/books/1", nil, "viewer").Body.Bytes(), &book)
+			Expect(book["price"], ShouldBeNil)
+			Expect(book["title"], ShouldNotBeNil)
+
+			json.Unmarshal(request("GET", "/books/1", nil, "admin").Body.Bytes(), &book)
+			Expect(book["price"], ShouldEqual, 10)
+		})
+
+		It("uses the role of the Identity", func() {
+			faker.SetAuth(&Auth{ApiKeys: map[string]*Identity{"key-1": {Subject: "ci", Role: "admin"}}, DefaultPublic: true})
+			defer faker.SetAuth(nil)
+
+			req := httptest.NewRequest("DELETE", "/books/1", nil)
+			req.Header.Set("X-Api-Key", "key-1")
+			req.Header.Set(RoleHeader, "editor")
+			rw := httptest.NewRecorder()
+			faker.ServeHTTP(rw, req)
+			Expect(rw.Code, ShouldEqual, http.StatusOK)
+		})
+
+		It("ignores RoleHeader if Auth is present", func() {
+			faker.SetAuth(&Auth{ApiKeys: map[string]*Identity{"key-1": {Subject: "ci", Role: "admin"}}, DefaultPublic: true})
+			defer faker.SetAuth(nil)
+
+			Expect(request("POST", "/books", url.Values{"title": {"b"}, "price": {"1"}}, "admin").Code, ShouldEqual, http.StatusForbidden)
+		})
+	})
+}
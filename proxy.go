@@ -0,0 +1,406 @@
+package apifaker
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProxyMode the mode of Proxy
+type ProxyMode string
+
+const (
+	// ProxyRecord forwards requests to the upstream and records the responses
+	ProxyRecord ProxyMode = "record"
+
+	// ProxyReplay serves requests with the recorded responses without the upstream
+	ProxyReplay ProxyMode = "replay"
+)
+
+// RecordedRequest the request of an Interaction
+type RecordedRequest struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Query  string `json:"query,omitempty"`
+	Body   string `json:"body,omitempty"`
+}
+
+// RecordedResponse the response of an Interaction
+type RecordedResponse struct {
+	Status  int         `json:"status"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    string      `json:"body,omitempty"`
+
+	// Encoding is "base64" if Body is base64 encoded because it is not valid UTF-8
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// bodyEncodingBase64 the Encoding of a base64 encoded RecordedResponse.Body
+const bodyEncodingBase64 = "base64"
+
+// Interaction a request and its response recorded in a cassette
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// key returns the key to match the Interaction
+func (request RecordedRequest) key() string {
+	return request.Method + " " + request.Path + "?" + request.Query + "\n" + request.Body
+}
+
+// Proxy records the responses of an upstream into a cassette or resource files,
+// and replays the recorded cassette offline
+type Proxy struct {
+	// Mode ProxyRecord or ProxyReplay
+	Mode ProxyMode
+
+	// Upstream the base url of the real api
+	Upstream *url.URL
+
+	// Cassette the ndjson file contains Interactions, one Interaction per line,
+	// if it is empty in ProxyRecord mode, GET responses are recorded into resource files
+	Cassette string
+
+	apiFaker     *ApiFaker
+	reverseProxy *httputil.ReverseProxy
+
+	// interactions uses RecordedRequest.key() as the key
+	interactions map[string]*Interaction
+
+	// payloads contains the recorded json bodies, uses resource name as the key
+	payloads map[string][][]byte
+
+	// recorded contains the names of resource files written by the Proxy
+	recorded map[string]bool
+	sync.Mutex
+}
+
+// Record forwards all requests of fake apis to the upstream and records the responses,
+// into the cassette if it is not empty, otherwise GET responses are recorded into resource files
+// in ApiDir, then NewWithApiDir can replay them offline
+func (af *ApiFaker) Record(upstream, cassette string) error {
+	upstreamURL, err := parseUpstream(upstream)
+	if err != nil {
+		return err
+	}
+
+	af.Proxy = &Proxy{
+		Mode:         ProxyRecord,
+		Upstream:     upstreamURL,
+		Cassette:     cassette,
+		apiFaker:     af,
+		reverseProxy: newReverseProxy(upstreamURL, af),
+		interactions: map[string]*Interaction{},
+		payloads:     map[string][][]byte{},
+		recorded:     map[string]bool{},
+	}
+	return nil
+}
+
+// Replay serves requests with the Interactions in the cassette,
+// other requests are served by the fake apis
+func (af *ApiFaker) Replay(cassette string) error {
+	proxy := &Proxy{
+		Mode:         ProxyReplay,
+		Cassette:     cassette,
+		apiFaker:     af,
+		interactions: map[string]*Interaction{},
+	}
+	if err := proxy.loadCassette(); err != nil {
+		return err
+	}
+
+	af.Proxy = proxy
+	return nil
+}
+
+// Fallthrough forwards the requests under Prefix which no fake api serves to the upstream,
+// so that only the resources in ApiDir are faked and the real backend serves the rest
+func (af *ApiFaker) Fallthrough(upstream string) error {
+	upstreamURL, err := parseUpstream(upstream)
+	if err != nil {
+		return err
+	}
+
+	af.fallthroughProxy = newReverseProxy(upstreamURL, af)
+	return nil
+}
+
+// fallthroughHandler forwards the request to the upstream of Fallthrough,
+// requests of resources and out of Prefix still get 404
+func (af *ApiFaker) fallthroughHandler(ctx *gin.Context) {
+	path := ctx.Request.URL.Path
+	if af.fallthroughProxy == nil || (af.Prefix != "" && !strings.HasPrefix(path, af.Prefix+"/")) {
+		return
+	}
+	if _, ok := af.routerOfPath(path); ok {
+		return
+	}
+
+	// hides the CloseNotify of gin.ResponseWriter which panics if the underlying writer does not support it
+	rw := struct{ http.ResponseWriter }{ctx.Writer}
+	af.fallthroughProxy.ServeHTTP(rw, ctx.Request)
+}
+
+// parseUpstream parses the upstream which must be an absolute url
+func parseUpstream(upstream string) (*url.URL, error) {
+	upstreamURL, err := url.Parse(upstream)
+	if err != nil || upstreamURL.Scheme == "" || upstreamURL.Host == "" {
+		return nil, ProxyErrorf("upstream must be an absolute url: %s", upstream)
+	}
+	return upstreamURL, nil
+}
+
+// newReverseProxy allocates and returns a new httputil.ReverseProxy to the upstream,
+// the Prefix of ApiFaker is trimmed from the request path
+func newReverseProxy(upstream *url.URL, af *ApiFaker) *httputil.ReverseProxy {
+	reverseProxy := httputil.NewSingleHostReverseProxy(upstream)
+	director := reverseProxy.Director
+	reverseProxy.Director = func(req *http.Request) {
+		req.URL.Path = strings.TrimPrefix(req.URL.Path, af.Prefix)
+		director(req)
+		req.Host = upstream.Host
+	}
+	return reverseProxy
+}
+
+// loadCassette reads the Interactions line by line from Cassette
+func (proxy *Proxy) loadCassette() error {
+	file, err := os.Open(proxy.Cassette)
+	if err != nil {
+		return ProxyErrorf("can not open cassette: %v", err)
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(bufio.NewReader(file))
+	for {
+		interaction := &Interaction{}
+		if err := decoder.Decode(interaction); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return ProxyErrorf("can not read cassette %s: %v", proxy.Cassette, err)
+		}
+		proxy.interactions[interaction.Request.key()] = interaction
+	}
+}
+
+// recordedRequestOf returns the RecordedRequest of the request, the body of request will be restored
+func recordedRequestOf(req *http.Request) RecordedRequest {
+	body := []byte{}
+	if req.Body != nil {
+		body, _ = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	return RecordedRequest{
+		Method: req.Method,
+		Path:   req.URL.Path,
+		Query:  req.URL.RawQuery,
+		Body:   string(body),
+	}
+}
+
+// ServeHTTP implements the http.Handler
+func (proxy *Proxy) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	switch proxy.Mode {
+	case ProxyRecord:
+		proxy.record(rw, req)
+	case ProxyReplay:
+		proxy.replay(rw, req)
+	}
+}
+
+// replay writes the recorded response, or lets the fake apis serve the request if it has not been recorded
+func (proxy *Proxy) replay(rw http.ResponseWriter, req *http.Request) {
+	proxy.Lock()
+	interaction, ok := proxy.interactions[recordedRequestOf(req).key()]
+	proxy.Unlock()
+
+	if !ok {
+		proxy.apiFaker.Engine.ServeHTTP(rw, req)
+		return
+	}
+
+	for key, values := range interaction.Response.Headers {
+		for _, value := range values {
+			rw.Header().Add(key, value)
+		}
+	}
+	rw.WriteHeader(interaction.Response.Status)
+	if interaction.Response.Encoding == bodyEncodingBase64 {
+		body, _ := base64.StdEncoding.DecodeString(interaction.Response.Body)
+		rw.Write(body)
+	} else {
+		io.WriteString(rw, interaction.Response.Body)
+	}
+}
+
+// record forwards the request to Upstream and records the response,
+// a body is not valid UTF-8 is recorded in base64
+func (proxy *Proxy) record(rw http.ResponseWriter, req *http.Request) {
+	request := recordedRequestOf(req)
+	recorder := &responseRecorder{ResponseWriter: rw, status: http.StatusOK}
+
+	// the transport asks for gzip by itself and decompresses the response without Accept-Encoding,
+	// so the recorded body is never compressed
+	req.Header.Del("Accept-Encoding")
+	proxy.reverseProxy.ServeHTTP(recorder, req)
+
+	interaction := &Interaction{
+		Request: request,
+		Response: RecordedResponse{
+			Status:  recorder.status,
+			Headers: recordedHeaders(rw.Header()),
+			Body:    recorder.body.String(),
+		},
+	}
+	if !utf8.Valid(recorder.body.Bytes()) {
+		interaction.Response.Body = base64.StdEncoding.EncodeToString(recorder.body.Bytes())
+		interaction.Response.Encoding = bodyEncodingBase64
+	}
+
+	var err error
+	if proxy.Cassette != "" {
+		err = proxy.appendCassette(interaction)
+	} else {
+		err = proxy.recordResource(interaction)
+	}
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// recordedHeaders returns the headers worth recording
+func recordedHeaders(header http.Header) http.Header {
+	recorded := http.Header{}
+	for key, values := range header {
+		switch http.CanonicalHeaderKey(key) {
+		case "Content-Length", "Connection", "Date", "Transfer-Encoding", "Keep-Alive":
+			continue
+		}
+		recorded[key] = values
+	}
+	return recorded
+}
+
+// appendCassette appends the Interaction into Cassette as a line
+func (proxy *Proxy) appendCassette(interaction *Interaction) error {
+	proxy.Lock()
+	defer proxy.Unlock()
+
+	bytes, err := json.Marshal(interaction)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(proxy.Cassette, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(bytes, '\n'))
+	proxy.interactions[interaction.Request.key()] = interaction
+	return err
+}
+
+// recordResource records the json body of a successful GET /collection or GET /collection/:id
+// into resource files, the columns are inferred from all recorded bodies of the resource,
+// existing resource files not written by the Proxy are never overwritten
+func (proxy *Proxy) recordResource(interaction *Interaction) error {
+	request, response := interaction.Request, interaction.Response
+	if request.Method != http.MethodGet || response.Status != http.StatusOK || response.Encoding != "" {
+		return nil
+	}
+
+	path := strings.Trim(strings.TrimPrefix(request.Path, proxy.apiFaker.Prefix), "/")
+	pieces := strings.Split(path, "/")
+	if path == "" || len(pieces) > 2 {
+		return nil
+	}
+
+	body := []byte(response.Body)
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return nil
+	}
+	if _, ok := objectsOf(value); !ok {
+		return nil
+	}
+
+	proxy.Lock()
+	defer proxy.Unlock()
+
+	name := pieces[0]
+	payloads := append(proxy.payloads[name], body)
+	models, err := Infer(name, payloads...)
+	if err != nil {
+		return ProxyErrorf("can not record %s %s: %v", request.Method, request.Path, err)
+	}
+
+	files := map[string][]byte{}
+	for i, model := range models {
+		// a nested resource recorded by itself should not be overwritten
+		if _, ok := proxy.payloads[model.Name]; i > 0 && ok {
+			continue
+		}
+
+		path := filepath.Join(proxy.apiFaker.ApiDir, model.Name+".json")
+		if _, err := os.Stat(path); err == nil && !proxy.recorded[model.Name] {
+			return ProxyErrorf("can not record %s %s: %s exists and was not written by the proxy", request.Method, request.Path, path)
+		}
+
+		bytes, err := json.MarshalIndent(model, "", "    ")
+		if err != nil {
+			return err
+		}
+		files[path] = bytes
+	}
+	proxy.payloads[name] = payloads
+
+	for _, model := range models {
+		path := filepath.Join(proxy.apiFaker.ApiDir, model.Name+".json")
+		bytes, ok := files[path]
+		if !ok {
+			continue
+		}
+		if err := ioutil.WriteFile(path, bytes, 0644); err != nil {
+			return err
+		}
+		proxy.recorded[model.Name] = true
+	}
+	return nil
+}
+
+// responseRecorder writes the response and keeps a copy of status and body
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
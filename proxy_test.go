@@ -0,0 +1,181 @@
+package apifaker
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProxy(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		switch req.URL.Path {
+		case "/users":
+			rw.Write([]byte(`[{"id": 1, "name": "Frank", "books": [{"id": 1, "title": "A"}]}, {"id": 2, "name": "Tony", "books": []}]`))
+		case "/users/2":
+			rw.Write([]byte(`{"id": 2, "name": "Antony", "books": [{"id": 2, "title": "B"}]}`))
+		case "/tags":
+			if !strings.Contains(req.Header.Get("Accept-Encoding"), "gzip") {
+				rw.Write([]byte(`[{"id": 1, "name": "go"}]`))
+				return
+			}
+			rw.Header().Set("Content-Encoding", "gzip")
+			writer := gzip.NewWriter(rw)
+			writer.Write([]byte(`[{"id": 1, "name": "go"}]`))
+			writer.Close()
+		case "/avatar.png":
+			rw.Header().Set("Content-Type", "image/png")
+			rw.Write([]byte{0x89, 0x50, 0x4e, 0x47, 0xff, 0xfe})
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+			rw.Write([]byte(`{"message": "not found"}`))
+		}
+	}))
+	defer upstream.Close()
+
+	request := func(faker *ApiFaker, path string) *httptest.ResponseRecorder {
+		rw := httptest.NewRecorder()
+		faker.ServeHTTP(rw, httptest.NewRequest("GET", path, nil))
+		return rw
+	}
+	requestGzip := func(faker *ApiFaker, path string) *httptest.ResponseRecorder {
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		faker.ServeHTTP(rw, req)
+		return rw
+	}
+
+	Describ("Record into resource files", t, func() {
+		dir, _ := ioutil.TempDir("", "apifaker")
+		defer os.RemoveAll(dir)
+		faker, _ := NewWithApiDir(dir)
+		err := faker.Record(upstream.URL, "")
+		request(faker, "/users")
+		response := request(faker, "/users/2")
+		tagsResponse := requestGzip(faker, "/tags")
+
+		It("forwards requests to the upstream", func() {
+			Expect(err, ShouldBeNil)
+			Expect(response.Body.String(), ShouldEqual, `{"id": 2, "name": "Antony", "books": [{"id": 2, "title": "B"}]}`)
+		})
+
+		It("writes resource files can be replayed offline", func() {
+			faker, err := NewWithApiDir(dir)
+			Expect(err, ShouldBeNil)
+			Expect(faker.Routers["users"].Model.Len(), ShouldEqual, 2)
+			Expect(faker.Routers["books"].Model.Len(), ShouldEqual, 2)
+			var user map[string]interface{}
+			json.Unmarshal(request(faker, "/users/2").Body.Bytes(), &user)
+			Expect(user, ShouldResemble, map[string]interface{}{
+				"id": float64(2), "name": "Antony",
+				"books": []interface{}{map[string]interface{}{"id": float64(2), "title": "B", "user_id": float64(2)}},
+			})
+		})
+
+		It("records the decompressed body of a gzip response", func() {
+			Expect(tagsResponse.Body.String(), ShouldEqual, `[{"id": 1, "name": "go"}]`)
+			faker, err := NewWithApiDir(dir)
+			Expect(err, ShouldBeNil)
+			Expect(faker.Routers["tags"].Model.Len(), ShouldEqual, 1)
+		})
+	})
+
+	Describ("Record into an existing resource file", t, func() {
+		dir, _ := ioutil.TempDir("", "apifaker")
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "users.json")
+		ioutil.WriteFile(path, []byte(`{"resource_name": "users", "columns": [{"name": "id", "type": "number"}]}`), 0644)
+		faker, _ := NewWithApiDir(dir)
+		faker.Record(upstream.URL, "")
+		response := request(faker, "/users")
+
+		It("forwards the request but does not overwrite the file", func() {
+			Expect(response.Code, ShouldEqual, http.StatusOK)
+			bytes, _ := ioutil.ReadFile(path)
+			Expect(string(bytes), ShouldEqual, `{"resource_name": "users", "columns": [{"name": "id", "type": "number"}]}`)
+			_, err := os.Stat(filepath.Join(dir, "books.json"))
+			Expect(os.IsNotExist(err), ShouldBeTrue)
+		})
+	})
+
+	Describ("Record into a cassette", t, func() {
+		dir, _ := ioutil.TempDir("", "apifaker")
+		defer os.RemoveAll(dir)
+		cassette := filepath.Join(dir, "cassette.ndjson")
+		faker, _ := NewWithApiDir(testDir)
+		faker.Record(upstream.URL, cassette)
+		request(faker, "/users/2")
+		request(faker, "/unknown")
+		requestGzip(faker, "/tags")
+		request(faker, "/avatar.png")
+
+		It("replays the recorded responses", func() {
+			faker, _ := NewWithApiDir(testDir)
+			err := faker.Replay(cassette)
+			Expect(err, ShouldBeNil)
+
+			response := request(faker, "/users/2")
+			Expect(response.Body.String(), ShouldEqual, `{"id": 2, "name": "Antony", "books": [{"id": 2, "title": "B"}]}`)
+			Expect(response.Header().Get("Content-Type"), ShouldEqual, "application/json")
+			Expect(request(faker, "/unknown").Code, ShouldEqual, http.StatusNotFound)
+			Expect(request(faker, "/tags").Body.String(), ShouldEqual, `[{"id": 1, "name": "go"}]`)
+			Expect(request(faker, "/avatar.png").Body.Bytes(), ShouldResemble, []byte{0x89, 0x50, 0x4e, 0x47, 0xff, 0xfe})
+		})
+
+		It("serves the requests which are not recorded with fake apis", func() {
+			faker, _ := NewWithApiDir(testDir)
+			faker.Replay(cassette)
+			Expect(request(faker, "/users/1").Code, ShouldEqual, http.StatusOK)
+		})
+	})
+
+	Describ("Record", t, func() {
+		Context("when upstream is not an absolute url", func() {
+			It("returns error", func() {
+				faker, _ := NewWithApiDir(testDir)
+				Expect(faker.Record("/api", ""), ShouldNotBeNil)
+			})
+		})
+	})
+
+	Describ("Fallthrough", t, func() {
+		faker, _ := NewWithApiDir(testDir)
+		err := faker.Fallthrough(upstream.URL)
+
+		It("fakes the resources in ApiDir", func() {
+			Expect(err, ShouldBeNil)
+			response := request(faker, "/users/1")
+			Expect(response.Code, ShouldEqual, http.StatusOK)
+			Expect(response.Body.String(), ShouldNotContainSubstring, "Antony")
+		})
+
+		It("forwards other paths to the upstream", func() {
+			Expect(request(faker, "/users/2/books").Code, ShouldEqual, http.StatusNotFound)
+			Expect(request(faker, "/unknown").Body.String(), ShouldEqual, `{"message": "not found"}`)
+		})
+
+		Context("when faker has a prefix", func() {
+			faker, _ := NewWithApiDir(testDir)
+			faker.Fallthrough(upstream.URL)
+			faker.MountTo("/fake_api")
+
+			It("forwards other paths under the prefix without the prefix", func() {
+				response := request(faker, "/fake_api/orders")
+				Expect(response.Body.String(), ShouldEqual, `{"message": "not found"}`)
+				Expect(request(faker, "/fake_api/users/1").Code, ShouldEqual, http.StatusOK)
+			})
+
+			It("does not forward paths out of the prefix", func() {
+				Expect(request(faker, "/orders").Body.String(), ShouldEqual, "404 page not found")
+			})
+		})
+	})
+}
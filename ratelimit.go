@@ -0,0 +1,228 @@
+package apifaker
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// rateLimitByIP identifies clients by their ip
+	rateLimitByIP = "ip"
+
+	// rateLimitByApiKey identifies clients by the api key header, see Auth.ApiKeyHeader
+	rateLimitByApiKey = "api_key"
+
+	// rateLimitByHeaderPrefix identifies clients by a header, like "header:X-Client-Id"
+	rateLimitByHeaderPrefix = "header:"
+)
+
+// RateLimit limits the requests of every client to a resource with a token bucket
+type RateLimit struct {
+	// Methods the methods the RateLimit applies to, empty means all methods
+	Methods []string `json:"methods,omitempty"`
+
+	// Limit the capacity of the bucket, a client can send Limit requests in every Period
+	Limit int `json:"limit"`
+
+	// Period the seconds to refill the whole bucket, default 60
+	Period float64 `json:"period,omitempty"`
+
+	// Key identifies clients, "ip"(default), "api_key" or "header:<name>",
+	// the ip is used if the request has no such header
+	Key string `json:"key,omitempty"`
+
+	buckets map[string]*bucket
+	lock    sync.Mutex
+}
+
+// bucket the tokens of a client
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+// CheckMeta sets the default values and checks
+//  1. Limit must be positive and Period must not be negative
+//  2. every element of Methods must be a RestMethod, they are upper cased
+//  3. Key must be "ip", "api_key" or "header:<name>"
+func (limit *RateLimit) CheckMeta() error {
+	if limit.Limit <= 0 {
+		return RateLimitErrorf("limit must be positive: %d", limit.Limit)
+	}
+	if limit.Period < 0 {
+		return RateLimitErrorf("period must not be negative: %v", limit.Period)
+	}
+	if limit.Period == 0 {
+		limit.Period = 60
+	}
+
+	for i, name := range limit.Methods {
+		method, err := ParseRestMethod(name)
+		if err != nil {
+			return RateLimitErrorf("%v", err)
+		}
+		limit.Methods[i] = method.String()
+	}
+
+	switch {
+	case limit.Key == "":
+		limit.Key = rateLimitByIP
+	case limit.Key == rateLimitByIP, limit.Key == rateLimitByApiKey:
+	case strings.HasPrefix(limit.Key, rateLimitByHeaderPrefix) && len(limit.Key) > len(rateLimitByHeaderPrefix):
+	default:
+		return RateLimitErrorf("unknown key: %s, must be %s, %s or %s<name>", limit.Key, rateLimitByIP, rateLimitByApiKey, rateLimitByHeaderPrefix)
+	}
+	return nil
+}
+
+// appliesTo returns if the RateLimit applies to the given method
+func (limit *RateLimit) appliesTo(method string) bool {
+	return len(limit.Methods) == 0 || containsString(limit.Methods, method)
+}
+
+// clientOf returns the key of the client who sends the request
+func (limit *RateLimit) clientOf(ctx *gin.Context, faker *ApiFaker) string {
+	header := ""
+	switch {
+	case limit.Key == rateLimitByApiKey:
+		header = defaultApiKeyHeader
+		if faker.Auth != nil {
+			header = faker.Auth.ApiKeyHeader
+		}
+	case strings.HasPrefix(limit.Key, rateLimitByHeaderPrefix):
+		header = strings.TrimPrefix(limit.Key, rateLimitByHeaderPrefix)
+	}
+
+	if header != "" {
+		if value := ctx.Request.Header.Get(header); value != "" {
+			return header + ":" + value
+		}
+	}
+	return "ip:" + ctx.ClientIP()
+}
+
+// take takes a token of the client from its bucket, returns the remaining tokens,
+// the time the bucket will be full, the seconds until the client gets a token, and if the request is allowed
+func (limit *RateLimit) take(client string, now time.Time) (int, time.Time, int, bool) {
+	limit.lock.Lock()
+	defer limit.lock.Unlock()
+
+	if limit.buckets == nil {
+		limit.buckets = map[string]*bucket{}
+	}
+	b, ok := limit.buckets[client]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Limit), updatedAt: now}
+		limit.buckets[client] = b
+	}
+
+	rate := float64(limit.Limit) / limit.Period
+	b.tokens = math.Min(float64(limit.Limit), b.tokens+now.Sub(b.updatedAt).Seconds()*rate)
+	b.updatedAt = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	full := now.Add(time.Duration((float64(limit.Limit) - b.tokens) / rate * float64(time.Second)))
+	retryAfter := int(math.Ceil((1 - b.tokens) / rate))
+	return int(b.tokens), full, retryAfter, allowed
+}
+
+// reset refills all buckets
+func (limit *RateLimit) reset() {
+	limit.lock.Lock()
+	defer limit.lock.Unlock()
+	limit.buckets = nil
+}
+
+// CheckRateLimitsMeta checks every RateLimit
+func (model *Model) CheckRateLimitsMeta() error {
+	for _, limit := range model.RateLimits {
+		if err := limit.CheckMeta(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ResetRateLimits refills the buckets of the resource with the given name, an empty name resets all resources
+func (af *ApiFaker) ResetRateLimits(name string) error {
+	routers := af.Routers
+	if name != "" {
+		router, ok := af.Routers[name]
+		if !ok {
+			return JsonFileErrorf("unknown resource: %s", name)
+		}
+		routers = map[string]*Router{name: router}
+	}
+
+	for _, router := range routers {
+		for _, limit := range router.Model.RateLimits {
+			limit.reset()
+		}
+	}
+	return nil
+}
+
+// RateLimitMiddleware returns a gin.HandlerFunc which limits the requests of resources with RateLimits,
+// it sets the X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset headers of the most restrictive RateLimit,
+// and responses 429 with Retry-After if the client runs out of tokens
+func RateLimitMiddleware(faker *ApiFaker) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		router, ok := faker.routerOfPath(ctx.Request.URL.Path)
+		if !ok {
+			return
+		}
+
+		now := time.Now()
+		minRemaining := -1
+		for _, limit := range router.Model.RateLimits {
+			if !limit.appliesTo(ctx.Request.Method) {
+				continue
+			}
+
+			remaining, full, retryAfter, allowed := limit.take(limit.clientOf(ctx, faker), now)
+			if minRemaining < 0 || remaining < minRemaining || !allowed {
+				minRemaining = remaining
+				ctx.Header("X-RateLimit-Limit", strconv.Itoa(limit.Limit))
+				ctx.Header("X-RateLimit-Remaining", strconv.Itoa(remaining))
+				ctx.Header("X-RateLimit-Reset", strconv.FormatInt(full.Unix(), 10))
+			}
+
+			if !allowed {
+				ctx.Header("Retry-After", strconv.Itoa(retryAfter))
+				ctx.JSON(http.StatusTooManyRequests, map[string]string{"message": "rate limit exceeded"})
+				ctx.Abort()
+				return
+			}
+		}
+	}
+}
+
+// setRateLimitHandlers sets the admin apis of RateLimits:
+//
+//	DELETE /_apifaker/rate_limits
+//	DELETE /_apifaker/rate_limits/:resource
+func (af *ApiFaker) setRateLimitHandlers() {
+	path := af.Prefix + adminPath + "/rate_limits"
+
+	af.DELETE(path, func(ctx *gin.Context) {
+		af.ResetRateLimits("")
+		ctx.JSON(http.StatusOK, nil)
+	})
+
+	af.DELETE(path+"/:resource", func(ctx *gin.Context) {
+		if err := af.ResetRateLimits(ctx.Param("resource")); err != nil {
+			ctx.JSON(http.StatusNotFound, ResponseErrorMsg(err))
+		} else {
+			ctx.JSON(http.StatusOK, nil)
+		}
+	})
+}
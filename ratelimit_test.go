@@ -0,0 +1,97 @@
+package apifaker
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "apifaker")
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "books.json"), []byte(`{"resource_name": "books",
+		"columns": [{"name": "id", "type": "number"}, {"name": "title", "type": "string"}],
+		"seeds": [{"id": 1, "title": "a"}],
+		"rate_limits": [{"methods": ["GET"], "limit": 2, "period": 60, "key": "header:X-Client-Id"}]}`), 0644)
+
+	faker, err := NewWithApiDir(dir)
+	faker.InMemory = true
+	request := func(method, path, client string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("X-Client-Id", client)
+		rw := httptest.NewRecorder()
+		faker.ServeHTTP(rw, req)
+		return rw
+	}
+
+	Describ("CheckMeta", t, func() {
+		It("checks the RateLimit", func() {
+			Expect(err, ShouldBeNil)
+			Expect((&RateLimit{}).CheckMeta(), ShouldNotBeNil)
+			Expect((&RateLimit{Limit: 1, Key: "cookie"}).CheckMeta(), ShouldNotBeNil)
+			Expect((&RateLimit{Limit: 1, Methods: []string{"FETCH"}}).CheckMeta(), ShouldNotBeNil)
+		})
+
+		It("upper cases Methods", func() {
+			limit := &RateLimit{Limit: 1, Methods: []string{"post"}}
+			Expect(limit.CheckMeta(), ShouldBeNil)
+			Expect(limit.appliesTo("POST"), ShouldBeTrue)
+			Expect(limit.appliesTo("GET"), ShouldBeFalse)
+		})
+	})
+
+	Describ("take", t, func() {
+		It("refills the bucket over time", func() {
+			limit := &RateLimit{Limit: 2, Period: 10}
+			now := time.Now()
+			limit.take("a", now)
+			remaining, _, _, allowed := limit.take("a", now)
+			Expect(allowed, ShouldBeTrue)
+			Expect(remaining, ShouldEqual, 0)
+
+			_, _, retryAfter, allowed := limit.take("a", now)
+			Expect(allowed, ShouldBeFalse)
+			Expect(retryAfter, ShouldEqual, 5)
+
+			_, _, _, allowed = limit.take("a", now.Add(5*time.Second))
+			Expect(allowed, ShouldBeTrue)
+		})
+	})
+
+	Describ("RateLimitMiddleware", t, func() {
+		faker.ResetRateLimits("")
+
+		It("responses 429 after the limit", func() {
+			response := request("GET", "/books/1", "a")
+			Expect(response.Code, ShouldEqual, http.StatusOK)
+			Expect(response.Header().Get("X-RateLimit-Limit"), ShouldEqual, "2")
+			Expect(response.Header().Get("X-RateLimit-Remaining"), ShouldEqual, "1")
+			Expect(response.Header().Get("X-RateLimit-Reset"), ShouldNotBeEmpty)
+
+			request("GET", "/books", "a")
+			response = request("GET", "/books/1", "a")
+			Expect(response.Code, ShouldEqual, http.StatusTooManyRequests)
+			Expect(response.Header().Get("Retry-After"), ShouldEqual, "30")
+		})
+
+		It("limits every client and method separately", func() {
+			request("GET", "/books/1", "a")
+			request("GET", "/books/1", "a")
+			Expect(request("GET", "/books/1", "b").Code, ShouldEqual, http.StatusOK)
+			Expect(request("PATCH", "/books/1", "a").Header().Get("X-RateLimit-Limit"), ShouldBeEmpty)
+		})
+
+		It("is reset by the admin api", func() {
+			request("GET", "/books/1", "a")
+			request("GET", "/books/1", "a")
+			Expect(request("DELETE", "/_apifaker/rate_limits/books", "").Code, ShouldEqual, http.StatusOK)
+			Expect(request("GET", "/books/1", "a").Code, ShouldEqual, http.StatusOK)
+			Expect(request("DELETE", "/_apifaker/rate_limits/foos", "").Code, ShouldEqual, http.StatusNotFound)
+		})
+	})
+}
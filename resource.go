@@ -0,0 +1,167 @@
+package apifaker
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Row a LineItem of a Resource as a map, numbers are float64 like in json
+type Row map[string]interface{}
+
+// Decode decodes the Row into v by json, like a struct with json tags
+func (row Row) Decode(v interface{}) error {
+	return decodeByJSON(row, v)
+}
+
+// Rows a list of Row sorted by id
+type Rows []Row
+
+// Decode decodes the Rows into v by json, like a pointer of a struct slice
+func (rows Rows) Decode(v interface{}) error {
+	return decodeByJSON(rows, v)
+}
+
+// Resource manipulates the data of a resource without HTTP,
+// it runs the same validation as the fake apis, changes are saved back to files unless InMemory is true
+type Resource struct {
+	model *Model
+	err   error
+}
+
+// Resource returns the Resource with the given name, all methods of it return an error if the resource is unknown
+func (af *ApiFaker) Resource(name string) *Resource {
+	model, err := af.modelOf(name)
+	return &Resource{model: model, err: err}
+}
+
+// Create validates and adds a new item with the given attrs, a map or a struct with json tags,
+// id is generated if it is absent or zero, auto columns are ignored, returns the created Row
+func (res *Resource) Create(attrs interface{}) (Row, error) {
+	values, err := res.values(attrs)
+	if err != nil {
+		return nil, err
+	}
+
+	if values["id"] == float64(0) {
+		delete(values, "id")
+	}
+	for _, column := range res.model.Columns {
+		if column.isAuto() {
+			delete(values, column.Name)
+		}
+	}
+
+	li := NewLineItemWithMap(values)
+	if err := res.model.Add(li); err != nil {
+		return nil, err
+	}
+	return Row(li.ToMap()), nil
+}
+
+// Find returns the Row with the given id, soft deleted items are not found
+func (res *Resource) Find(id float64) (Row, error) {
+	if res.err != nil {
+		return nil, res.err
+	}
+
+	li, ok := res.model.Get(id)
+	if !ok || li.isDeleted() {
+		return nil, fmt.Errorf("%s[id:%v] does not exist", res.model.Name, id)
+	}
+	return Row(li.ToMap()), nil
+}
+
+// Where returns the Rows match all the given conditions, like {"user_id": 1},
+// the values of conditions can also be a *Range, soft deleted items are excluded
+func (res *Resource) Where(conditions map[string]interface{}) (Rows, error) {
+	if res.err != nil {
+		return nil, res.err
+	}
+
+	normalized := map[string]interface{}{}
+	for name, value := range conditions {
+		if _, ok := value.(*Range); !ok {
+			if err := decodeByJSON(value, &value); err != nil {
+				return nil, err
+			}
+		}
+		normalized[name] = value
+	}
+
+	rows := Rows{}
+	for _, li := range res.model.Where(normalized).withoutDeleted() {
+		rows = append(rows, Row(li.ToMap()))
+	}
+	return rows, nil
+}
+
+// All returns all Rows, soft deleted items are excluded
+func (res *Resource) All() (Rows, error) {
+	return res.Where(nil)
+}
+
+// Update validates and updates the given attrs of the item with the given id like PATCH,
+// attrs is a map or a struct with json tags, id and auto columns are ignored, returns the updated Row
+func (res *Resource) Update(id float64, attrs interface{}) (Row, error) {
+	values, err := res.values(attrs)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := res.Find(id); err != nil {
+		return nil, err
+	}
+
+	li, err := res.model.updateWith(id, nil, func(column *Column) (interface{}, bool, error) {
+		value, ok := values[column.Name]
+		return value, ok, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return Row(li.ToMap()), nil
+}
+
+// Delete deletes the item with the given id like DELETE, its related data are deleted too unless SoftDelete is true
+func (res *Resource) Delete(id float64) error {
+	if _, err := res.Find(id); err != nil {
+		return err
+	}
+
+	res.model.Delete(id)
+	return nil
+}
+
+// Count returns the number of items, soft deleted items are excluded
+func (res *Resource) Count() int {
+	if res.err != nil {
+		return 0
+	}
+	return len(res.model.lineItems().withoutDeleted())
+}
+
+// values returns the attrs as a map with json values, every key must be a column
+func (res *Resource) values(attrs interface{}) (map[string]interface{}, error) {
+	if res.err != nil {
+		return nil, res.err
+	}
+
+	values := map[string]interface{}{}
+	if err := decodeByJSON(attrs, &values); err != nil {
+		return nil, err
+	}
+	for name := range values {
+		if !res.model.hasColumn(name) {
+			return nil, ColumnsErrorf("resource %s has no column %s", res.model.Name, name)
+		}
+	}
+	return values, nil
+}
+
+// decodeByJSON encodes the value into json and decodes it into v
+func decodeByJSON(value interface{}, v interface{}) error {
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(bytes, v)
+}
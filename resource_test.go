@@ -0,0 +1,94 @@
+package apifaker
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+type testBook struct {
+	Id     int    `json:"id"`
+	Title  string `json:"title"`
+	UserId int    `json:"user_id"`
+}
+
+func TestResource(t *testing.T) {
+	faker, _ := NewWithApiDir(testDir)
+	faker.InMemory = true
+	books := faker.Resource("books")
+
+	Describ("Resource", t, func() {
+		It("returns errors of unknown resources", func() {
+			_, err := faker.Resource("foos").Find(1)
+			Expect(err, ShouldNotBeNil)
+			Expect(faker.Resource("foos").Count(), ShouldEqual, 0)
+		})
+	})
+
+	Describ("Create", t, func() {
+		It("validates and creates an item", func() {
+			row, err := books.Create(testBook{Title: "Dune", UserId: 2})
+			Expect(err, ShouldBeNil)
+			Expect(row["id"], ShouldBeGreaterThan, 3)
+			defer books.Delete(row["id"].(float64))
+
+			_, err = books.Create(map[string]interface{}{"title": "Dune", "user_id": 2})
+			Expect(err, ShouldNotBeNil)
+			_, err = books.Create(map[string]interface{}{"title": "Emma", "user_id": 100})
+			Expect(err, ShouldNotBeNil)
+			_, err = books.Create(map[string]interface{}{"title": "Emma", "user_id": 1, "foo": 1})
+			Expect(err, ShouldNotBeNil)
+		})
+	})
+
+	Describ("Find and Where", t, func() {
+		It("decodes the rows", func() {
+			row, err := books.Find(1)
+			Expect(err, ShouldBeNil)
+			book := testBook{}
+			Expect(row.Decode(&book), ShouldBeNil)
+			Expect(book, ShouldResemble, testBook{Id: 1, Title: "The Little Prince", UserId: 1})
+
+			rows, err := books.Where(map[string]interface{}{"user_id": 1})
+			Expect(err, ShouldBeNil)
+			list := []testBook{}
+			Expect(rows.Decode(&list), ShouldBeNil)
+			Expect(len(list), ShouldEqual, 2)
+			Expect(list[1].Title, ShouldEqual, "The Alchemist")
+
+			_, err = books.Find(100)
+			Expect(err, ShouldNotBeNil)
+		})
+	})
+
+	Describ("Update, Delete and Count", t, func() {
+		It("updates and deletes the item", func() {
+			row, _ := books.Create(map[string]interface{}{"title": "Emma", "user_id": 1})
+			id := row["id"].(float64)
+			count := books.Count()
+
+			row, err := books.Update(id, map[string]interface{}{"title": "Persuasion"})
+			Expect(err, ShouldBeNil)
+			Expect(row["title"], ShouldEqual, "Persuasion")
+			Expect(row["user_id"], ShouldEqual, 1)
+
+			_, err = books.Update(id, map[string]interface{}{"title": "Life of Pi"})
+			Expect(err, ShouldNotBeNil)
+
+			Expect(books.Delete(id), ShouldBeNil)
+			Expect(books.Count(), ShouldEqual, count-1)
+			Expect(books.Delete(id), ShouldNotBeNil)
+		})
+
+		It("leaves the item unchanged if the update fails", func() {
+			users := faker.Resource("users")
+			_, err := users.Update(1, map[string]interface{}{"name": "Zed", "phone": "999"})
+			Expect(err, ShouldNotBeNil)
+
+			row, _ := users.Find(1)
+			Expect(row["name"], ShouldEqual, "Frank")
+			Expect(row["phone"], ShouldEqual, "13213213213")
+			_, err = users.Update(2, map[string]interface{}{"name": "Frank"})
+			Expect(err, ShouldNotBeNil)
+		})
+	})
+}
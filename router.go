@@ -2,6 +2,10 @@ package apifaker
 
 import (
 	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/jinzhu/inflection"
 )
 
 type RestMethod int
@@ -14,6 +18,30 @@ const (
 	DELETE
 )
 
+// restMethodNames maps RestMethod to its http method name
+var restMethodNames = map[RestMethod]string{
+	GET:    "GET",
+	POST:   "POST",
+	PUT:    "PUT",
+	PATCH:  "PATCH",
+	DELETE: "DELETE",
+}
+
+// String returns the http method name of RestMethod
+func (method RestMethod) String() string {
+	return restMethodNames[method]
+}
+
+// ParseRestMethod returns the RestMethod of the given http method name
+func ParseRestMethod(name string) (RestMethod, error) {
+	for method, methodName := range restMethodNames {
+		if strings.EqualFold(methodName, name) {
+			return method, nil
+		}
+	}
+	return 0, fmt.Errorf("unsupportted method: %s", name)
+}
+
 type Route struct {
 	// Method request method only supports GET, POST, PUT, PATCH, DELETE
 	Method RestMethod
@@ -50,6 +78,11 @@ func (r *Router) setRestRoutes() {
 		// DELETE /collection
 		{DELETE, fmt.Sprintf("/%s/:id", r.Model.Name)},
 	}
+
+	if r.Model.SoftDelete {
+		// POST /collection/:id/restore
+		r.Routes = append(r.Routes, Route{POST, fmt.Sprintf("/%s/:id/restore", r.Model.Name)})
+	}
 }
 
 // SaveToFile
@@ -69,3 +102,29 @@ func NewRouterWithPath(path string, apiFaker *ApiFaker) (*Router, error) {
 	router.setRestRoutes()
 	return router, err
 }
+
+// inflections caches the results of inflection, which is slow to be called for every LineItem
+var inflections = struct {
+	plurals   sync.Map
+	singulars sync.Map
+}{}
+
+// plural returns the cached plural form of the given name
+func plural(name string) string {
+	if value, ok := inflections.plurals.Load(name); ok {
+		return value.(string)
+	}
+	value := inflection.Plural(name)
+	inflections.plurals.Store(name, value)
+	return value
+}
+
+// singular returns the cached singular form of the given name
+func singular(name string) string {
+	if value, ok := inflections.singulars.Load(name); ok {
+		return value.(string)
+	}
+	value := inflection.Singular(name)
+	inflections.singulars.Store(name, value)
+	return value
+}
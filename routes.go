@@ -0,0 +1,258 @@
+package apifaker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"text/template"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CustomRoute describes an endpoint which is not CRUD, like "GET /me" or "POST /login"
+type CustomRoute struct {
+	// Method the request method, only supports GET, POST, PUT, PATCH, DELETE
+	Method string `json:"method"`
+
+	// Path the path relative to Prefix, supports params like "/users/:id/summary"
+	Path string `json:"path"`
+
+	// Status the status code of the response, default 200
+	Status int `json:"status,omitempty"`
+
+	// Headers the extra headers of the response
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// Body the json body of the response
+	Body interface{} `json:"body,omitempty"`
+
+	// BodyTemplate the text/template of the response body, it can use functions:
+	//   1. param "name": the path param
+	//   2. query "name": the query param
+	//   3. body "name": the field of the json body or the form value
+	//   4. find "resource" id: the transformed item with related data of the resource, nil if not found
+	//   5. where "resource" "column" value: the transformed items of the resource whose column equals to the value
+	//   6. json value: the json of the value
+	BodyTemplate string `json:"body_template,omitempty"`
+
+	template *template.Template
+}
+
+// routesFile the content of a json file contains only routes
+type routesFile struct {
+	Name   string         `json:"resource_name"`
+	Routes []*CustomRoute `json:"routes"`
+}
+
+// CheckMeta checks
+//  1. Method must be a RestMethod
+//  2. Path must start with "/"
+//  3. Status must be a valid status code if it is present
+//  4. Body and BodyTemplate can not be both present
+//  5. BodyTemplate must be a valid template
+func (route *CustomRoute) CheckMeta() error {
+	if _, err := ParseRestMethod(route.Method); err != nil {
+		return RoutesErrorf("%v", err)
+	}
+
+	if !strings.HasPrefix(route.Path, "/") {
+		return RoutesErrorf("path must start with \"/\": %s", route.Path)
+	}
+
+	if route.Status != 0 && (route.Status < 100 || route.Status > 999) {
+		return RoutesErrorf("status must be a valid status code: %d", route.Status)
+	}
+
+	if route.Body != nil && route.BodyTemplate != "" {
+		return RoutesErrorf("body and body_template of %s %s can not be both present", route.Method, route.Path)
+	}
+
+	if route.BodyTemplate != "" {
+		tmpl, err := template.New(route.Path).Funcs(templateFuncs(nil, nil)).Parse(route.BodyTemplate)
+		if err != nil {
+			return RoutesErrorf("wrong body_template of %s %s: %v", route.Method, route.Path, err)
+		}
+		route.template = tmpl
+	}
+
+	return nil
+}
+
+// loadRoutesFile returns the routes of the json file if it contains only routes without resource_name
+func loadRoutesFile(path string) ([]*CustomRoute, bool, error) {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, false, err
+	}
+
+	file := &routesFile{}
+	if err := json.Unmarshal(bytes, file); err != nil {
+		return nil, false, JsonFileErrorf("%s has wrong json format: %v", path, err)
+	}
+	if file.Name != "" || file.Routes == nil {
+		return nil, false, nil
+	}
+
+	for _, route := range file.Routes {
+		if err := route.CheckMeta(); err != nil {
+			return nil, true, err
+		}
+	}
+	return file.Routes, true, nil
+}
+
+// allCustomRoutes returns the CustomRoutes of routes files and all Models
+func (af *ApiFaker) allCustomRoutes() []*CustomRoute {
+	routes := append([]*CustomRoute{}, af.CustomRoutes...)
+	for _, router := range af.Routers {
+		routes = append(routes, router.Model.CustomRoutes...)
+	}
+	return routes
+}
+
+// CheckRoutes checks if any CustomRoute or route of Auth conflicts with other routes
+func (af *ApiFaker) CheckRoutes() (err error) {
+	engine := gin.New()
+	noop := func(*gin.Context) {}
+	defer func() {
+		if r := recover(); r != nil {
+			err = RoutesErrorf("%v", r)
+		}
+	}()
+
+	for _, router := range af.Routers {
+		for _, route := range router.Routes {
+			engine.Handle(route.Method.String(), route.Path, noop)
+		}
+	}
+	for _, route := range af.allCustomRoutes() {
+		engine.Handle(strings.ToUpper(route.Method), route.Path, noop)
+	}
+	if af.Auth != nil {
+		for _, route := range af.Auth.routes() {
+			engine.Handle(route.Method.String(), route.Path, noop)
+		}
+	}
+	return nil
+}
+
+// setCustomRouteHandlers sets handlers of all CustomRoutes
+func (af *ApiFaker) setCustomRouteHandlers() {
+	for _, route := range af.allCustomRoutes() {
+		route := route
+		af.Handle(strings.ToUpper(route.Method), af.Prefix+route.Path, func(ctx *gin.Context) {
+			route.serve(ctx, af)
+		})
+	}
+}
+
+// serve writes the response of the CustomRoute
+func (route *CustomRoute) serve(ctx *gin.Context, af *ApiFaker) {
+	status := route.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	for key, value := range route.Headers {
+		ctx.Header(key, value)
+	}
+
+	if route.template == nil {
+		if route.Body == nil {
+			ctx.Status(status)
+		} else {
+			ctx.JSON(status, route.Body)
+		}
+		return
+	}
+
+	tmpl, err := route.template.Clone()
+	if err == nil {
+		buffer := &bytes.Buffer{}
+		err = tmpl.Funcs(templateFuncs(ctx, af)).Execute(buffer, nil)
+		if err == nil {
+			if ctx.Writer.Header().Get("Content-Type") == "" {
+				ctx.Header("Content-Type", "application/json; charset=utf-8")
+			}
+			ctx.Status(status)
+			ctx.Writer.Write(buffer.Bytes())
+			return
+		}
+	}
+	ctx.JSON(http.StatusInternalServerError, ResponseErrorMsg(RoutesErrorf("%v", err)))
+}
+
+// templateFuncs returns the functions of body templates for the request
+func templateFuncs(ctx *gin.Context, af *ApiFaker) template.FuncMap {
+	var jsonBody map[string]interface{}
+	return template.FuncMap{
+		"param": func(name string) string { return ctx.Param(name) },
+		"query": func(name string) string { return ctx.Query(name) },
+		"body": func(name string) interface{} {
+			if !strings.Contains(ctx.ContentType(), "json") {
+				return ctx.PostForm(name)
+			}
+			if jsonBody == nil {
+				jsonBody = map[string]interface{}{}
+				if bytes, err := ioutil.ReadAll(ctx.Request.Body); err == nil {
+					json.Unmarshal(bytes, &jsonBody)
+				}
+			}
+			return jsonBody[name]
+		},
+		"find": func(name string, id interface{}) (interface{}, error) {
+			model, err := af.modelOf(name)
+			if err != nil {
+				return nil, err
+			}
+			idFloat64, ok := toNumber(id)
+			if !ok {
+				return nil, nil
+			}
+			li, ok := model.Get(idFloat64)
+			if !ok || li.isDeleted() {
+				return nil, nil
+			}
+			if ownerId, ok := model.ownerIdOf(ctx); ok && !model.isOwnedBy(li, ownerId) {
+				return nil, nil
+			}
+			return model.transform(model.readable(ctx, li.InsertRelatedData(model))), nil
+		},
+		"where": func(name, columnName string, value interface{}) ([]map[string]interface{}, error) {
+			model, err := af.modelOf(name)
+			if err != nil {
+				return nil, err
+			}
+			if str, ok := value.(string); ok {
+				for _, column := range model.Columns {
+					if column.Name == columnName {
+						if value, err = FormatValue(column.Type, str); err != nil {
+							return nil, err
+						}
+					}
+				}
+			}
+			items := []map[string]interface{}{}
+			lis := model.ownedOnly(ctx, model.Where(map[string]interface{}{columnName: value})).withoutDeleted()
+			for _, li := range model.readableAll(ctx, lis) {
+				items = append(items, model.transform(li))
+			}
+			return items, nil
+		},
+		"json": func(value interface{}) (string, error) {
+			bytes, err := json.Marshal(value)
+			return string(bytes), err
+		},
+	}
+}
+
+// modelOf returns the Model of the resource with the given name
+func (af *ApiFaker) modelOf(name string) (*Model, error) {
+	router, ok := af.Routers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown resource %s", name)
+	}
+	return router.Model, nil
+}
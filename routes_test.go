@@ -0,0 +1,142 @@
+package apifaker
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCustomRoutes(t *testing.T) {
+	faker, err := NewWithApiDir(testDir)
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rw := httptest.NewRecorder()
+		faker.ServeHTTP(rw, req)
+		return rw
+	}
+
+	Describ("NewWithApiDir", t, func() {
+		It("loads the routes file", func() {
+			Expect(err, ShouldBeNil)
+			Expect(len(faker.CustomRoutes), ShouldEqual, 4)
+			Expect(len(faker.Routers), ShouldEqual, 3)
+		})
+	})
+
+	Describ("CustomRoute", t, func() {
+		Context("when uses find", func() {
+			It("responses the item with related data", func() {
+				response := request("GET", "/me", "")
+				Expect(response.Code, ShouldEqual, http.StatusOK)
+				Expect(response.Body.String(), ShouldStartWith, `{"age":22,"avatar":`)
+				Expect(response.Header().Get("Content-Type"), ShouldStartWith, "application/json")
+			})
+		})
+
+		Context("when uses body", func() {
+			It("responses with the status and the headers", func() {
+				response := request("POST", "/login", `{"name": "Frank"}`)
+				Expect(response.Code, ShouldEqual, http.StatusCreated)
+				Expect(response.Header().Get("X-Token-Type"), ShouldEqual, "fake")
+				Expect(response.Body.String(), ShouldEqual, `{"token": "token-Frank"}`)
+			})
+		})
+
+		Context("when uses param and where", func() {
+			It("responses the items matched", func() {
+				Expect(request("GET", "/users/1/books_count", "").Body.String(), ShouldEqual, `{"count": 2}`)
+				Expect(request("GET", "/users/1", "").Code, ShouldEqual, http.StatusOK)
+			})
+		})
+
+		Context("when has a static body", func() {
+			It("responses the body", func() {
+				Expect(request("GET", "/health", "").Body.String(), ShouldEqual, `{"status":"ok"}`)
+			})
+		})
+	})
+
+	Describ("CheckMeta", t, func() {
+		Context("when body and body_template are both present", func() {
+			It("returns error", func() {
+				route := &CustomRoute{Method: "GET", Path: "/me", Body: "x", BodyTemplate: "x"}
+				Expect(route.CheckMeta(), ShouldNotBeNil)
+			})
+		})
+
+		Context("when body_template is wrong", func() {
+			It("returns error", func() {
+				route := &CustomRoute{Method: "GET", Path: "/me", BodyTemplate: "{{ unknown }}"}
+				Expect(route.CheckMeta(), ShouldNotBeNil)
+			})
+		})
+	})
+
+	Describ("CheckRoutes", t, func() {
+		Context("when a route conflicts with a resource route", func() {
+			dir, _ := ioutil.TempDir("", "apifaker")
+			defer os.RemoveAll(dir)
+			ioutil.WriteFile(filepath.Join(dir, "tags.json"), []byte(`{"resource_name": "tags",
+				"columns": [{"name": "id", "type": "number"}], "seeds": [],
+				"routes": [{"method": "GET", "path": "/tags/:name"}]}`), 0644)
+
+			It("returns error", func() {
+				_, err := NewWithApiDir(dir)
+				Expect(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
+
+func TestTemplateFuncsScope(t *testing.T) {
+	dir := newAuthTestDir()
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "posts.json"), []byte(`{"resource_name": "posts", "soft_delete": true, "owner_column": "account_id",
+		"columns": [{"name": "id", "type": "number"}, {"name": "title", "type": "string"}, {"name": "account_id", "type": "number"}],
+		"seeds": [{"id": 1, "title": "a", "account_id": 1}, {"id": 2, "title": "b", "account_id": 2}, {"id": 3, "title": "c", "account_id": 1}],
+		"permissions": {"*": {"methods": ["GET", "DELETE"], "read": ["title"]}}}`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "routes.json"), []byte(`{"routes": [
+		{"method": "GET", "path": "/peek/:id", "body_template": "{{ find \"posts\" (param \"id\") | json }}"},
+		{"method": "GET", "path": "/by/:id", "body_template": "{{ where \"posts\" \"account_id\" (param \"id\") | json }}"}]}`), 0644)
+
+	faker, _ := NewWithApiDir(dir)
+	faker.InMemory = true
+	faker.SetAuth(&Auth{Basic: true, UserResource: "accounts"})
+	request := func(method, path string) string {
+		req := httptest.NewRequest(method, path, nil)
+		req.SetBasicAuth("frank", "secret")
+		rw := httptest.NewRecorder()
+		faker.ServeHTTP(rw, req)
+		return rw.Body.String()
+	}
+
+	Describ("templateFuncs", t, func() {
+		Context("when uses find", func() {
+			It("hides the items and the columns hidden from the GET routes", func() {
+				Expect(request("GET", "/peek/1"), ShouldEqual, `{"id":1,"title":"a"}`)
+				Expect(request("GET", "/peek/2"), ShouldEqual, "null")
+			})
+		})
+
+		Context("when uses where", func() {
+			It("hides the items and the columns hidden from the GET routes", func() {
+				Expect(request("GET", "/by/1"), ShouldEqual, `[{"id":1,"title":"a"},{"id":3,"title":"c"}]`)
+				Expect(request("GET", "/by/2"), ShouldEqual, "[]")
+			})
+		})
+
+		Context("when an item is soft deleted", func() {
+			request("DELETE", "/posts/3")
+			It("hides the item", func() {
+				Expect(request("GET", "/peek/3"), ShouldEqual, "null")
+				Expect(request("GET", "/by/1"), ShouldEqual, `[{"id":1,"title":"a"}]`)
+			})
+		})
+	})
+}
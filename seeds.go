@@ -0,0 +1,207 @@
+package apifaker
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	csvExt    = ".csv"
+	ndjsonExt = ".ndjson"
+)
+
+// seedsFilePath returns the absolute path of the SeedsFile,
+// a relative SeedsFile is relative to the given dir
+func (model *Model) seedsFilePath(dir string) string {
+	if filepath.IsAbs(model.SeedsFile) {
+		return model.SeedsFile
+	}
+	return filepath.Join(dir, model.SeedsFile)
+}
+
+// loadSeedsFile reads the seeds line by line from the SeedsFile into Seeds
+func (model *Model) loadSeedsFile(dir string) error {
+	if model.SeedsFile == "" {
+		return nil
+	}
+
+	if len(model.Seeds) > 0 {
+		return SeedsErrorf("model[name=\"%s\"] can not use both seeds and seeds_file", model.Name)
+	}
+
+	file, err := os.Open(model.seedsFilePath(dir))
+	if err != nil {
+		return SeedsErrorf("can not open seeds_file %s: %v", model.SeedsFile, err)
+	}
+	defer file.Close()
+
+	switch strings.ToLower(filepath.Ext(model.SeedsFile)) {
+	case csvExt:
+		return model.readCSVSeeds(file)
+	case ndjsonExt:
+		return model.readNDJSONSeeds(file)
+	}
+
+	return SeedsErrorf("seeds_file %s must be a %s or %s file", model.SeedsFile, csvExt, ndjsonExt)
+}
+
+// readCSVSeeds reads seeds from r, the first record must be the column names
+func (model *Model) readCSVSeeds(r io.Reader) error {
+	reader := csv.NewReader(bufio.NewReader(r))
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return SeedsErrorf("can not read header of %s: %v", model.SeedsFile, err)
+	}
+
+	columnTypes := map[string]string{}
+	for _, column := range model.Columns {
+		columnTypes[column.Name] = column.Type
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return SeedsErrorf("can not read %s: %v", model.SeedsFile, err)
+		}
+
+		seed := map[string]interface{}{}
+		for i, name := range header {
+			columnType, ok := columnTypes[name]
+			if !ok {
+				return SeedsErrorf("unknown column \"%s\" in %s", name, model.SeedsFile)
+			}
+
+			// formatCSVField writes null as an empty field
+			if record[i] == "" && columnType != str.Name() {
+				seed[name] = nil
+				continue
+			}
+
+			value, err := FormatValue(columnType, record[i])
+			if err != nil {
+				return SeedsErrorf("column[name=\"%s\"] has wrong value %q in %s: %v", name, record[i], model.SeedsFile, err)
+			}
+			seed[name] = value
+		}
+		model.Seeds = append(model.Seeds, seed)
+	}
+}
+
+// readNDJSONSeeds reads seeds from r, every line is a json object
+func (model *Model) readNDJSONSeeds(r io.Reader) error {
+	decoder := json.NewDecoder(bufio.NewReader(r))
+	for {
+		seed := map[string]interface{}{}
+		if err := decoder.Decode(&seed); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return SeedsErrorf("can not read %s: %v", model.SeedsFile, err)
+		}
+		model.Seeds = append(model.Seeds, seed)
+	}
+}
+
+// saveSeedsFile writes all LineItems line by line into the SeedsFile,
+// it writes a temporary file first and then renames it to the SeedsFile
+func (model *Model) saveSeedsFile(dir string) error {
+	path := model.seedsFilePath(dir)
+	file, err := os.Create(path + ".tmp")
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(file)
+	lis := model.lineItems()
+	switch strings.ToLower(filepath.Ext(model.SeedsFile)) {
+	case csvExt:
+		err = model.writeCSVSeeds(writer, lis)
+	case ndjsonExt:
+		err = model.writeNDJSONSeeds(writer, lis)
+	default:
+		err = SeedsErrorf("seeds_file %s must be a %s or %s file", model.SeedsFile, csvExt, ndjsonExt)
+	}
+
+	if err == nil {
+		err = writer.Flush()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path + ".tmp")
+		return err
+	}
+
+	return os.Rename(path+".tmp", path)
+}
+
+// writeCSVSeeds writes a header of column names and one record for every LineItem
+func (model *Model) writeCSVSeeds(w io.Writer, lis LineItems) error {
+	writer := csv.NewWriter(w)
+	header := make([]string, len(model.Columns))
+	for i, column := range model.Columns {
+		header[i] = column.Name
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for _, li := range lis {
+		record := make([]string, len(model.Columns))
+		for i, column := range model.Columns {
+			value, _ := li.Get(column.Name)
+			field, err := formatCSVField(value)
+			if err != nil {
+				return err
+			}
+			record[i] = field
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// writeNDJSONSeeds writes every LineItem as a json object in one line
+func (model *Model) writeNDJSONSeeds(w io.Writer, lis LineItems) error {
+	encoder := json.NewEncoder(w)
+	for _, li := range lis {
+		if err := encoder.Encode(li.ToMap()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatCSVField formats the given value into a csv field,
+// it is the reverse of FormatValue, null is an empty field and is read back as null unless the column is a string
+func formatCSVField(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+
+	bytes, err := json.Marshal(value)
+	return string(bytes), err
+}
@@ -0,0 +1,91 @@
+package apifaker
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// deletedAtKey the key of the time when a LineItem is soft deleted, it is not a Column
+	deletedAtKey = "deleted_at"
+
+	// withDeletedParam the query param to include the soft deleted LineItems
+	withDeletedParam = "with_deleted"
+)
+
+// isDeleted returns if the LineItem has been soft deleted
+func (li LineItem) isDeleted() bool {
+	value, ok := li.Get(deletedAtKey)
+	return ok && value != nil
+}
+
+// withDeleted returns if the request asks for the soft deleted LineItems
+func withDeleted(ctx *gin.Context) bool {
+	return ctx.Query(withDeletedParam) == "true"
+}
+
+// checkDeletedAt checks the deleted_at of the seed,
+// it must be null or a datetime if the Model uses soft delete, otherwise it must be absent
+func (model *Model) checkDeletedAt(seed map[string]interface{}) error {
+	value, ok := seed[deletedAtKey]
+	if !ok || model.hasColumn(deletedAtKey) {
+		return nil
+	}
+
+	if !model.SoftDelete {
+		return SeedsErrorf("model %s does not use soft delete, but has %s in seed: %v", model.Name, deletedAtKey, seed)
+	}
+	if value == nil {
+		return nil
+	}
+	if str, ok := value.(string); ok {
+		if _, err := time.Parse(time.RFC3339, str); err == nil {
+			return nil
+		}
+	}
+	return SeedsErrorf("%s must be null or in RFC 3339 format, value: %v", deletedAtKey, value)
+}
+
+// softDelete sets deleted_at of the LineItem with the given id, the related data is kept
+func (model *Model) softDelete(li LineItem) {
+	newLi := NewLineItemWithMap(li.ToMap())
+	newLi.Set(deletedAtKey, now())
+	model.Store.Update(newLi)
+	model.dataChanged = true
+}
+
+// Restore removes deleted_at of the soft deleted LineItem with the given id,
+// returns the restored LineItem
+func (model *Model) Restore(id float64) (LineItem, error) {
+	model.Lock()
+	defer model.Unlock()
+
+	li, ok := model.Get(id)
+	if !ok {
+		return li, SeedsErrorf("model %s[id:%v] does not exsit", model.Name, id)
+	}
+	if !li.isDeleted() {
+		return li, fmt.Errorf("model %s[id:%v] has not been deleted", model.Name, id)
+	}
+
+	newLi := NewLineItemWithMap(li.ToMap())
+	delete(newLi.dataMap, deletedAtKey)
+	if err := model.Store.Update(newLi); err != nil {
+		return li, err
+	}
+	model.dataChanged = true
+	return newLi, nil
+}
+
+// withoutDeleted returns the LineItems which have not been soft deleted
+func (lis LineItems) withoutDeleted() LineItems {
+	kept := LineItems{}
+	for _, li := range lis {
+		if !li.isDeleted() {
+			kept = append(kept, li)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,86 @@
+package apifaker
+
+import (
+	"encoding/json"
+	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSoftDelete(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "apifaker")
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "notes.json"), []byte(`{"resource_name": "notes", "soft_delete": true, "has_many": ["comments"],
+		"columns": [{"name": "id", "type": "number"}, {"name": "title", "type": "string"}],
+		"seeds": [{"id": 1, "title": "a"}, {"id": 2, "title": "b", "deleted_at": "2020-01-01T00:00:00Z"}]}`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "comments.json"), []byte(`{"resource_name": "comments",
+		"columns": [{"name": "id", "type": "number"}, {"name": "note_id", "type": "number"}],
+		"seeds": [{"id": 1, "note_id": 1}]}`), 0644)
+
+	faker, err := NewWithApiDir(dir)
+	faker.InMemory = true
+	request := func(method, path string) (int, interface{}) {
+		rw := httptest.NewRecorder()
+		faker.ServeHTTP(rw, httptest.NewRequest(method, path, nil))
+
+		var body interface{}
+		json.Unmarshal(rw.Body.Bytes(), &body)
+		return rw.Code, body
+	}
+
+	Describ("soft delete", t, func() {
+		It("hides the soft deleted items", func() {
+			Expect(err, ShouldBeNil)
+			_, body := request("GET", "/notes")
+			Expect(len(body.([]interface{})), ShouldEqual, 1)
+
+			code, _ := request("GET", "/notes/2")
+			Expect(code, ShouldEqual, http.StatusNotFound)
+		})
+
+		It("shows the soft deleted items with with_deleted=true", func() {
+			_, body := request("GET", "/notes?with_deleted=true")
+			Expect(len(body.([]interface{})), ShouldEqual, 2)
+
+			code, body := request("GET", "/notes/2?with_deleted=true")
+			Expect(code, ShouldEqual, http.StatusOK)
+			Expect(body.(map[string]interface{})["deleted_at"], ShouldEqual, "2020-01-01T00:00:00Z")
+		})
+
+		It("sets deleted_at on DELETE and keeps related data", func() {
+			code, _ := request("DELETE", "/notes/1")
+			Expect(code, ShouldEqual, http.StatusOK)
+
+			li, ok := faker.Routers["notes"].Model.Get(1)
+			Expect(ok, ShouldBeTrue)
+			Expect(li.isDeleted(), ShouldBeTrue)
+			Expect(faker.Routers["comments"].Model.Has(1), ShouldBeTrue)
+
+			code, _ = request("PATCH", "/notes/1")
+			Expect(code, ShouldEqual, http.StatusNotFound)
+		})
+
+		It("restores the soft deleted item", func() {
+			code, body := request("POST", "/notes/2/restore")
+			Expect(code, ShouldEqual, http.StatusOK)
+			Expect(body.(map[string]interface{})["deleted_at"], ShouldBeNil)
+
+			code, _ = request("GET", "/notes/2")
+			Expect(code, ShouldEqual, http.StatusOK)
+
+			code, _ = request("POST", "/notes/2/restore")
+			Expect(code, ShouldEqual, http.StatusBadRequest)
+		})
+	})
+
+	Describ("Delete of a resource without soft_delete", t, func() {
+		It("has no restore route", func() {
+			code, _ := request("POST", "/comments/1/restore")
+			Expect(code, ShouldEqual, http.StatusNotFound)
+		})
+	})
+}
@@ -0,0 +1,343 @@
+package apifaker
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/Focinfi/gset"
+)
+
+const (
+	memoryStore = "memory"
+	fileStore   = "file"
+)
+
+// Store contains the runtime data of a Model
+type Store interface {
+	// Get returns the LineItem with the given id and its existence
+	Get(id float64) (LineItem, bool)
+
+	// List returns all LineItems sorted by id
+	List() LineItems
+
+	// Insert adds the given LineItem, returns error if its id exists
+	Insert(li LineItem) error
+
+	// Update replaces the LineItem which has the same id with the given LineItem
+	Update(li LineItem) error
+
+	// Delete removes the LineItem with the given id
+	Delete(id float64) error
+
+	// Len returns the count of LineItems
+	Len() int
+
+	// Snapshot makes the current data durable
+	Snapshot() error
+
+	// Close releases the resources held by the Store
+	Close() error
+}
+
+// MemoryStore keeps LineItems in memory only
+type MemoryStore struct {
+	set *gset.SetThreadSafe
+}
+
+// NewMemoryStore allocates and returns a new MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{set: gset.NewSetThreadSafe()}
+}
+
+// Get implements Store
+func (s *MemoryStore) Get(id float64) (li LineItem, ok bool) {
+	var element interface{}
+	if element, ok = s.set.Get(id); ok {
+		li, ok = element.(LineItem)
+	}
+	return
+}
+
+// List implements Store
+func (s *MemoryStore) List() LineItems {
+	lis := LineItems{}
+	for _, element := range s.set.ToSlice() {
+		if li, ok := element.(LineItem); ok {
+			lis = append(lis, li)
+		}
+	}
+	sort.Sort(lis)
+	return lis
+}
+
+// Insert implements Store
+func (s *MemoryStore) Insert(li LineItem) error {
+	if s.set.Has(gset.T(li.ID())) {
+		return SeedsErrorf("item[id=%v] already exists", li.ID())
+	}
+	s.set.Add(li)
+	return nil
+}
+
+// Update implements Store
+func (s *MemoryStore) Update(li LineItem) error {
+	if !s.set.Has(gset.T(li.ID())) {
+		return SeedsErrorf("item[id=%v] does not exsit", li.ID())
+	}
+	s.set.Add(li)
+	return nil
+}
+
+// Delete implements Store
+func (s *MemoryStore) Delete(id float64) error {
+	s.set.Remove(gset.T(id))
+	return nil
+}
+
+// Len implements Store
+func (s *MemoryStore) Len() int {
+	return s.set.Len()
+}
+
+// Snapshot implements Store, data in memory can not be durable
+func (s *MemoryStore) Snapshot() error {
+	return nil
+}
+
+// Close implements Store
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// fileStoreOp is one line of FileStore's file
+type fileStoreOp struct {
+	Op   string                 `json:"op"`
+	Id   float64                `json:"id,omitempty"`
+	Item map[string]interface{} `json:"item,omitempty"`
+}
+
+const (
+	fileStorePut    = "put"
+	fileStoreDelete = "delete"
+)
+
+// FileStore keeps LineItems in memory and appends every change into a file,
+// so a change only writes one line instead of rewriting the whole file,
+// Snapshot compacts the file to contain only the current LineItems,
+// a FileStore opened by NewReadOnlyFileStore never writes the file
+type FileStore struct {
+	path  string
+	file  *os.File
+	items map[float64]LineItem
+
+	// existed signs if the file existed before opening
+	existed bool
+	sync.RWMutex
+}
+
+// NewFileStore allocates and returns a new FileStore using the given path,
+// it replays the changes in file if the file exists
+func NewFileStore(path string) (*FileStore, error) {
+	return openFileStore(path, true)
+}
+
+// NewReadOnlyFileStore allocates and returns a new FileStore restored from the file at path if it exists,
+// changes are kept in memory only and the file is never created or written
+func NewReadOnlyFileStore(path string) (*FileStore, error) {
+	return openFileStore(path, false)
+}
+
+// openFileStore restores the FileStore from the file at path, and opens the file for appending if writable is true
+func openFileStore(path string, writable bool) (*FileStore, error) {
+	s := &FileStore{path: path, items: map[float64]LineItem{}}
+
+	if file, err := os.Open(path); err == nil {
+		s.existed = true
+		err = s.replay(file)
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	if !writable {
+		return s, nil
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	s.file = file
+	return s, nil
+}
+
+// replay applies every change in r
+func (s *FileStore) replay(r io.Reader) error {
+	decoder := json.NewDecoder(bufio.NewReader(r))
+	for {
+		op := fileStoreOp{}
+		if err := decoder.Decode(&op); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return SeedsErrorf("can not read store file %s: %v", s.path, err)
+		}
+
+		switch op.Op {
+		case fileStorePut:
+			li := NewLineItemWithMap(op.Item)
+			s.items[li.ID()] = li
+		case fileStoreDelete:
+			delete(s.items, op.Id)
+		}
+	}
+}
+
+// append writes the op into file as a line
+func (s *FileStore) append(op fileStoreOp) error {
+	if s.file == nil {
+		return nil
+	}
+
+	bytes, err := json.Marshal(op)
+	if err != nil {
+		return err
+	}
+	_, err = s.file.Write(append(bytes, '\n'))
+	return err
+}
+
+// Existed returns if the file existed when the FileStore was opened
+func (s *FileStore) Existed() bool {
+	return s.existed
+}
+
+// Get implements Store
+func (s *FileStore) Get(id float64) (LineItem, bool) {
+	s.RLock()
+	defer s.RUnlock()
+	li, ok := s.items[id]
+	return li, ok
+}
+
+// List implements Store
+func (s *FileStore) List() LineItems {
+	s.RLock()
+	defer s.RUnlock()
+	return s.list()
+}
+
+// list returns all LineItems sorted by id, the caller must hold the lock
+func (s *FileStore) list() LineItems {
+	lis := make(LineItems, 0, len(s.items))
+	for _, li := range s.items {
+		lis = append(lis, li)
+	}
+	sort.Sort(lis)
+	return lis
+}
+
+// Insert implements Store
+func (s *FileStore) Insert(li LineItem) error {
+	s.Lock()
+	defer s.Unlock()
+	if _, ok := s.items[li.ID()]; ok {
+		return SeedsErrorf("item[id=%v] already exists", li.ID())
+	}
+	if err := s.append(fileStoreOp{Op: fileStorePut, Item: li.ToMap()}); err != nil {
+		return err
+	}
+	s.items[li.ID()] = li
+	return nil
+}
+
+// Update implements Store
+func (s *FileStore) Update(li LineItem) error {
+	s.Lock()
+	defer s.Unlock()
+	if _, ok := s.items[li.ID()]; !ok {
+		return SeedsErrorf("item[id=%v] does not exsit", li.ID())
+	}
+	if err := s.append(fileStoreOp{Op: fileStorePut, Item: li.ToMap()}); err != nil {
+		return err
+	}
+	s.items[li.ID()] = li
+	return nil
+}
+
+// Delete implements Store
+func (s *FileStore) Delete(id float64) error {
+	s.Lock()
+	defer s.Unlock()
+	if _, ok := s.items[id]; !ok {
+		return nil
+	}
+	if err := s.append(fileStoreOp{Op: fileStoreDelete, Id: id}); err != nil {
+		return err
+	}
+	delete(s.items, id)
+	return nil
+}
+
+// Len implements Store
+func (s *FileStore) Len() int {
+	s.RLock()
+	defer s.RUnlock()
+	return len(s.items)
+}
+
+// Snapshot implements Store, it rewrites the file with the current LineItems
+func (s *FileStore) Snapshot() error {
+	s.Lock()
+	defer s.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	lis := s.list()
+
+	// the new file is kept open for appending after the rename,
+	// the old one is kept to append changes if anything fails
+	tmpPath := s.path + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for _, li := range lis {
+		if err = encoder.Encode(fileStoreOp{Op: fileStorePut, Item: li.ToMap()}); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, s.path)
+	}
+	if err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+
+	s.file.Close()
+	s.file = file
+	return nil
+}
+
+// Close implements Store
+func (s *FileStore) Close() error {
+	s.Lock()
+	defer s.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	return s.file.Close()
+}
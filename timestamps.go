@@ -0,0 +1,145 @@
+package apifaker
+
+import (
+	"time"
+)
+
+const (
+	// autoCreate sets the current time when a LineItem is added
+	autoCreate = "create"
+
+	// autoUpdate sets the current time when a LineItem is added or updated
+	autoUpdate = "update"
+)
+
+// rangeOperators the suffixes of query params for range filters
+var rangeOperators = []string{"_gt", "_gte", "_lt", "_lte"}
+
+// now returns the current time in RFC 3339 format
+func now() string {
+	return time.Now().UTC().Format(time.RFC3339)
+}
+
+// isAuto returns if the value of the Column is managed automatically
+func (column *Column) isAuto() bool {
+	return column.Auto != ""
+}
+
+// setAutoValues sets the values of auto Columns for the LineItem,
+// oldLi is nil when the LineItem is being added:
+//  1. "create" Columns use the current time if they have no value when added, then keep the value of oldLi
+//  2. "update" Columns use the current time whenever the LineItem is added or updated
+func (model *Model) setAutoValues(li LineItem, oldLi *LineItem) {
+	current := now()
+	for _, column := range model.Columns {
+		switch column.Auto {
+		case autoCreate:
+			if oldLi != nil {
+				if value, ok := oldLi.Get(column.Name); ok {
+					li.Set(column.Name, value)
+				}
+			} else if value, ok := li.Get(column.Name); !ok || value == "" {
+				li.Set(column.Name, current)
+			}
+		case autoUpdate:
+			li.Set(column.Name, current)
+		}
+	}
+}
+
+// fillSeedsAutoValues sets the current time for the auto Columns which are missing in Seeds
+func (model *Model) fillSeedsAutoValues() error {
+	current := now()
+	for _, seed := range model.Seeds {
+		for _, column := range model.Columns {
+			if _, ok := seed[column.Name]; !ok && column.isAuto() {
+				seed[column.Name] = current
+			}
+		}
+	}
+	return nil
+}
+
+// Range the condition matches values in a range, nil bounds are ignored,
+// numbers are compared by value, datetimes are compared by time
+type Range struct {
+	Gt  interface{}
+	Gte interface{}
+	Lt  interface{}
+	Lte interface{}
+}
+
+// set sets the bound of the operator
+func (r *Range) set(operator string, value interface{}) {
+	switch operator {
+	case "_gt":
+		r.Gt = value
+	case "_gte":
+		r.Gte = value
+	case "_lt":
+		r.Lt = value
+	case "_lte":
+		r.Lte = value
+	}
+}
+
+// Contains returns if the value is in the Range
+func (r *Range) Contains(value interface{}) bool {
+	bounds := []struct {
+		bound interface{}
+		ok    func(int) bool
+	}{
+		{r.Gt, func(c int) bool { return c > 0 }},
+		{r.Gte, func(c int) bool { return c >= 0 }},
+		{r.Lt, func(c int) bool { return c < 0 }},
+		{r.Lte, func(c int) bool { return c <= 0 }},
+	}
+
+	for _, b := range bounds {
+		if b.bound == nil {
+			continue
+		}
+		c, ok := compare(value, b.bound)
+		if !ok || !b.ok(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// compare returns -1, 0 or 1 if a is less than, equal to or greater than b,
+// and false if they are not comparable
+func compare(a, b interface{}) (int, bool) {
+	switch av := a.(type) {
+	case float64:
+		bv, ok := b.(float64)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case av < bv:
+			return -1, true
+		case av > bv:
+			return 1, true
+		}
+		return 0, true
+	case string:
+		bv, ok := b.(string)
+		if !ok {
+			return 0, false
+		}
+		at, aErr := time.Parse(time.RFC3339, av)
+		bt, bErr := time.Parse(time.RFC3339, bv)
+		if aErr != nil || bErr != nil {
+			return 0, false
+		}
+		switch {
+		case at.Before(bt):
+			return -1, true
+		case at.After(bt):
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
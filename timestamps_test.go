@@ -0,0 +1,104 @@
+package apifaker
+
+import (
+	"encoding/json"
+	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTimestamps(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "apifaker")
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "posts.json"), []byte(`{"resource_name": "posts",
+		"columns": [{"name": "id", "type": "number"}, {"name": "title", "type": "string"},
+			{"name": "published_at", "type": "datetime"},
+			{"name": "created_at", "type": "datetime", "auto": "create"},
+			{"name": "updated_at", "type": "datetime", "auto": "update"}],
+		"seeds": [
+			{"id": 1, "title": "a", "published_at": "2020-01-01T00:00:00Z", "created_at": "2020-01-01T00:00:00Z", "updated_at": "2020-01-01T00:00:00Z"},
+			{"id": 2, "title": "b", "published_at": "2020-06-01T00:00:00+08:00"}
+		]}`), 0644)
+
+	faker, err := NewWithApiDir(dir)
+	faker.InMemory = true
+	request := func(method, path string, form url.Values) (int, interface{}) {
+		req := httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rw := httptest.NewRecorder()
+		faker.ServeHTTP(rw, req)
+
+		var body interface{}
+		json.Unmarshal(rw.Body.Bytes(), &body)
+		return rw.Code, body
+	}
+
+	Describ("auto columns", t, func() {
+		It("fills the missing values of seeds", func() {
+			Expect(err, ShouldBeNil)
+			li, _ := faker.Routers["posts"].Model.Get(2)
+			createdAt, _ := li.Get("created_at")
+			Expect(createdAt, ShouldNotBeEmpty)
+		})
+
+		It("sets timestamps on POST", func() {
+			code, body := request("POST", "/posts", url.Values{"title": {"c"}, "published_at": {"2021-01-01T00:00:00Z"}})
+			Expect(code, ShouldEqual, http.StatusOK)
+			post := body.(map[string]interface{})
+			Expect(post["created_at"], ShouldNotBeEmpty)
+			Expect(post["updated_at"], ShouldEqual, post["created_at"])
+		})
+
+		It("keeps created_at and refreshes updated_at on PATCH and PUT", func() {
+			_, body := request("PATCH", "/posts/1", url.Values{"title": {"aa"}, "created_at": {"2000-01-01T00:00:00Z"}})
+			post := body.(map[string]interface{})
+			Expect(post["created_at"], ShouldEqual, "2020-01-01T00:00:00Z")
+			Expect(post["updated_at"], ShouldNotEqual, "2020-01-01T00:00:00Z")
+
+			_, body = request("PUT", "/posts/1", url.Values{"title": {"aaa"}, "published_at": {"2020-01-01T00:00:00Z"}})
+			post = body.(map[string]interface{})
+			Expect(post["created_at"], ShouldEqual, "2020-01-01T00:00:00Z")
+		})
+	})
+
+	Describ("datetime", t, func() {
+		Context("when the value is not in RFC 3339 format", func() {
+			It("responses 400", func() {
+				code, _ := request("POST", "/posts", url.Values{"title": {"d"}, "published_at": {"2021-01-01"}})
+				Expect(code, ShouldEqual, http.StatusBadRequest)
+			})
+		})
+
+		Context("when filters by range", func() {
+			It("responses the items in the range", func() {
+				_, body := request("GET", "/posts?published_at_gte=2020-01-01T00:00:00Z&published_at_lt=2020-06-01T00:00:00Z", nil)
+				posts := body.([]interface{})
+				Expect(len(posts), ShouldEqual, 2)
+				Expect(posts[1].(map[string]interface{})["title"], ShouldEqual, "b")
+
+				_, body = request("GET", "/posts?id_gt=1&id_lte=2", nil)
+				Expect(len(body.([]interface{})), ShouldEqual, 1)
+			})
+
+			It("responses 400 if the value is wrong", func() {
+				code, _ := request("GET", "/posts?published_at_gt=yesterday", nil)
+				Expect(code, ShouldEqual, http.StatusBadRequest)
+			})
+		})
+	})
+
+	Describ("Column.CheckMeta", t, func() {
+		Context("when an auto column is not datetime", func() {
+			It("returns error", func() {
+				Expect(Column{Name: "created_at", Type: "string", Auto: "create"}.CheckMeta(), ShouldNotBeNil)
+				Expect(Column{Name: "created_at", Type: "datetime", Auto: "always"}.CheckMeta(), ShouldNotBeNil)
+			})
+		})
+	})
+}
@@ -0,0 +1,194 @@
+package apifaker
+
+import (
+	"bytes"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	camelCase = "camel"
+	snakeCase = "snake"
+)
+
+// Transform describes how to shape the responses of a resource
+type Transform struct {
+	// KeyCase renames the keys of responses, "camel" or "snake",
+	// the renamed keys are also accepted on writing and filtering
+	KeyCase string `json:"key_case,omitempty"`
+
+	// Root wraps a single item into an object with the key, like {"user": {...}}
+	Root string `json:"root,omitempty"`
+
+	// CollectionRoot wraps items into an object with the key, like {"users": [...]}
+	CollectionRoot string `json:"collection_root,omitempty"`
+
+	// Computed the fields computed by text/template with the item as data, like "{{.first_name}} {{.last_name}}"
+	Computed map[string]string `json:"computed,omitempty"`
+
+	// Hidden the columns removed from responses, they are still accepted on writing
+	Hidden []string `json:"hidden,omitempty"`
+
+	templates map[string]*template.Template
+}
+
+// CheckMeta checks
+//  1. KeyCase must be "camel" or "snake" if it is present
+//  2. every element of Computed must be a valid template
+func (transform *Transform) CheckMeta() error {
+	switch transform.KeyCase {
+	case "", camelCase, snakeCase:
+	default:
+		return TransformErrorf("unknown key_case: %s, must be %s or %s", transform.KeyCase, camelCase, snakeCase)
+	}
+
+	transform.templates = map[string]*template.Template{}
+	for name, text := range transform.Computed {
+		tmpl, err := template.New(name).Option("missingkey=zero").Parse(text)
+		if err != nil {
+			return TransformErrorf("wrong template of computed field %s: %v", name, err)
+		}
+		transform.templates[name] = tmpl
+	}
+	return nil
+}
+
+// key returns the renamed key by KeyCase
+func (transform *Transform) key(name string) string {
+	if transform == nil {
+		return name
+	}
+
+	switch transform.KeyCase {
+	case camelCase:
+		return toCamelCase(name)
+	case snakeCase:
+		return toSnakeCase(name)
+	}
+	return name
+}
+
+// apply allocates and returns a new map transformed from the item
+func (transform *Transform) apply(item map[string]interface{}) map[string]interface{} {
+	if transform == nil {
+		return item
+	}
+
+	computed := map[string]interface{}{}
+	for name, tmpl := range transform.templates {
+		buffer := &bytes.Buffer{}
+		if err := tmpl.Execute(buffer, item); err != nil {
+			computed[name] = nil
+		} else {
+			computed[name] = buffer.String()
+		}
+	}
+
+	hidden := map[string]bool{}
+	for _, name := range transform.Hidden {
+		hidden[name] = true
+	}
+
+	newItem := map[string]interface{}{}
+	for key, value := range item {
+		if !hidden[key] {
+			newItem[transform.key(key)] = value
+		}
+	}
+	for name, value := range computed {
+		newItem[transform.key(name)] = value
+	}
+	return newItem
+}
+
+// CheckTransformMeta checks Transform if it is present, every hidden column must exist
+func (model *Model) CheckTransformMeta() error {
+	if model.Transform == nil {
+		return nil
+	}
+
+	for _, name := range model.Transform.Hidden {
+		if !model.hasColumn(name) {
+			return TransformErrorf("hidden column %s of resource %s does not exist", name, model.Name)
+		}
+	}
+	return model.Transform.CheckMeta()
+}
+
+// transform returns the transformed map of the LineItem
+func (model *Model) transform(li LineItem) map[string]interface{} {
+	return model.Transform.apply(li.ToMap())
+}
+
+// render returns the response of the transformed item, wrapped into Root if it is present
+func (model *Model) render(item map[string]interface{}) interface{} {
+	if model.Transform != nil && model.Transform.Root != "" {
+		return map[string]interface{}{model.Transform.Root: item}
+	}
+	return item
+}
+
+// renderAll returns the response of the LineItems with related data, wrapped into CollectionRoot if it is present
+func (model *Model) renderAll(lis LineItems) interface{} {
+	items := []map[string]interface{}{}
+	for _, li := range lis {
+		items = append(items, model.transform(li.InsertRelatedData(model)))
+	}
+	if model.Transform != nil && model.Transform.CollectionRoot != "" {
+		return map[string]interface{}{model.Transform.CollectionRoot: items}
+	}
+	return items
+}
+
+// queryValue returns the query param of the column and its existence, the renamed key by KeyCase is also accepted
+func (model *Model) queryValue(ctx *gin.Context, name string) (string, bool) {
+	if value, ok := ctx.GetQuery(name); ok {
+		return value, true
+	}
+	if key := model.Transform.key(name); key != name {
+		return ctx.GetQuery(key)
+	}
+	return "", false
+}
+
+// formValue returns the form value of the column, the renamed key by KeyCase is also accepted
+func (model *Model) formValue(ctx *gin.Context, name string) string {
+	if value := ctx.PostForm(name); value != "" {
+		return value
+	}
+	if key := model.Transform.key(name); key != name {
+		return ctx.PostForm(key)
+	}
+	return ""
+}
+
+// toCamelCase converts a snake_case name into camelCase, like "user_id" into "userId"
+func toCamelCase(name string) string {
+	pieces := strings.Split(name, "_")
+	for i := 1; i < len(pieces); i++ {
+		if pieces[i] != "" {
+			pieces[i] = strings.ToUpper(pieces[i][:1]) + pieces[i][1:]
+		}
+	}
+	return strings.Join(pieces, "")
+}
+
+// toSnakeCase converts a camelCase name into snake_case, like "userId" into "user_id",
+// an upper case acronym is kept as one word, like "UserID" into "user_id"
+func toSnakeCase(name string) string {
+	source := []rune(name)
+	runes := []rune{}
+	for i, r := range source {
+		if unicode.IsUpper(r) {
+			if i > 0 && (!unicode.IsUpper(source[i-1]) || i+1 < len(source) && unicode.IsLower(source[i+1])) {
+				runes = append(runes, '_')
+			}
+			r = unicode.ToLower(r)
+		}
+		runes = append(runes, r)
+	}
+	return string(runes)
+}
@@ -0,0 +1,88 @@
+package apifaker
+
+import (
+	"encoding/json"
+	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTransform(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "apifaker")
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "accounts.json"), []byte(`{"resource_name": "accounts",
+		"columns": [{"name": "id", "type": "number"}, {"name": "first_name", "type": "string"},
+			{"name": "last_name", "type": "string"}, {"name": "password", "type": "string"}],
+		"seeds": [{"id": 1, "first_name": "Frank", "last_name": "Lee", "password": "secret"}],
+		"transform": {"key_case": "camel", "root": "account", "collection_root": "accounts",
+			"computed": {"full_name": "{{.first_name}} {{.last_name}}"}, "hidden": ["password"]}}`), 0644)
+
+	faker, err := NewWithApiDir(dir)
+	faker.InMemory = true
+	request := func(method, path string, form url.Values) map[string]interface{} {
+		req := httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rw := httptest.NewRecorder()
+		faker.ServeHTTP(rw, req)
+
+		var body map[string]interface{}
+		json.Unmarshal(rw.Body.Bytes(), &body)
+		return body
+	}
+
+	Describ("Transform", t, func() {
+		It("renames keys, adds computed fields, hides columns and wraps into root", func() {
+			Expect(err, ShouldBeNil)
+			Expect(request("GET", "/accounts/1", nil), ShouldResemble, map[string]interface{}{
+				"account": map[string]interface{}{
+					"id": float64(1), "firstName": "Frank", "lastName": "Lee", "fullName": "Frank Lee",
+				},
+			})
+		})
+
+		It("wraps items into collection root", func() {
+			body := request("GET", "/accounts?firstName=Frank", nil)
+			Expect(len(body["accounts"].([]interface{})), ShouldEqual, 1)
+		})
+
+		It("accepts renamed keys and hidden columns on writing", func() {
+			body := request("POST", "/accounts", url.Values{
+				"firstName": {"Tony"}, "last_name": {"Stark"}, "password": {"jarvis"},
+			})
+			Expect(body["account"].(map[string]interface{})["fullName"], ShouldEqual, "Tony Stark")
+
+			li, _ := faker.Routers["accounts"].Model.Get(2)
+			password, _ := li.Get("password")
+			Expect(password, ShouldEqual, "jarvis")
+		})
+	})
+
+	Describ("CheckTransformMeta", t, func() {
+		Context("when a hidden column does not exist", func() {
+			It("returns error", func() {
+				model := &Model{Name: "accounts", Transform: &Transform{Hidden: []string{"foo"}}}
+				Expect(model.CheckTransformMeta(), ShouldNotBeNil)
+			})
+		})
+
+		Context("when key_case is unknown", func() {
+			It("returns error", func() {
+				Expect((&Transform{KeyCase: "kebab"}).CheckMeta(), ShouldNotBeNil)
+			})
+		})
+	})
+
+	Describ("toCamelCase and toSnakeCase", t, func() {
+		It("converts names", func() {
+			Expect(toCamelCase("user_id"), ShouldEqual, "userId")
+			Expect(toSnakeCase("userId"), ShouldEqual, "user_id")
+			Expect(toSnakeCase("UserID"), ShouldEqual, "user_id")
+			Expect(toSnakeCase("HTTPStatus"), ShouldEqual, "http_status")
+		})
+	})
+}
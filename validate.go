@@ -0,0 +1,331 @@
+package apifaker
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Problem describes an issue found in an api json file or a seeds file
+type Problem struct {
+	// File the path of the file
+	File string `json:"file"`
+
+	// Pointer the JSON pointer of the issue in File, like "/columns/1/type"
+	Pointer string `json:"pointer"`
+
+	// Message describes the issue
+	Message string `json:"message"`
+}
+
+// String returns the Problem in format "file#pointer: message"
+func (p Problem) String() string {
+	return fmt.Sprintf("%s#%s: %s", p.File, p.Pointer, p.Message)
+}
+
+// jsonPointer joins the given tokens into a JSON pointer
+func jsonPointer(tokens ...interface{}) string {
+	pointer := ""
+	for _, token := range tokens {
+		escaped := strings.Replace(fmt.Sprint(token), "~", "~0", -1)
+		escaped = strings.Replace(escaped, "/", "~1", -1)
+		pointer += "/" + escaped
+	}
+	return pointer
+}
+
+// validator collects Problems of all files in a directory
+type validator struct {
+	problems []Problem
+
+	// models uses resource name as the key
+	models map[string]*Model
+
+	// files uses resource name as the key
+	files map[string]string
+
+	// ids contains ids of seeds, uses resource name as the key
+	ids map[string]map[interface{}]bool
+}
+
+func (v *validator) add(file, pointer, format string, a ...interface{}) {
+	v.problems = append(v.problems, Problem{File: file, Pointer: pointer, Message: fmt.Sprintf(format, a...)})
+}
+
+// Validate checks all api json files in the given dir, returns every Problem found:
+//  1. json format and meta of resources, columns, relationships, latency, faults, transform and routes
+//  2. unknown resources in has_many and has_one
+//  3. values, duplicate ids, unique violations and dangling foreign keys of seeds
+//
+// unlike NewWithApiDir, it does not stop at the first error
+func Validate(dir string) []Problem {
+	v := &validator{
+		models: map[string]*Model{},
+		files:  map[string]string{},
+		ids:    map[string]map[interface{}]bool{},
+	}
+
+	err := filepath.Walk(dir, func(path string, f os.FileInfo, err error) error {
+		if f == nil {
+			return err
+		}
+		if f.IsDir() || !strings.HasSuffix(path, ".json") {
+			return nil
+		}
+		v.loadModel(path)
+		return nil
+	})
+	if err != nil {
+		v.add(dir, "", "%v", err)
+	}
+
+	for _, model := range v.sortedModels() {
+		v.checkRelationships(model)
+		v.checkSeeds(model)
+	}
+
+	return v.problems
+}
+
+// sortedModels returns models sorted by their file path
+func (v *validator) sortedModels() []*Model {
+	paths := []string{}
+	modelsByPath := map[string]*Model{}
+	for name, model := range v.models {
+		paths = append(paths, v.files[name])
+		modelsByPath[v.files[name]] = model
+	}
+	sort.Strings(paths)
+
+	models := []*Model{}
+	for _, path := range paths {
+		models = append(models, modelsByPath[path])
+	}
+	return models
+}
+
+// loadModel reads the file and checks the meta of the Model
+func (v *validator) loadModel(path string) {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		v.add(path, "", "%v", err)
+		return
+	}
+
+	model := NewModel(&Router{filePath: path})
+	if err := json.Unmarshal(bytes, model); err != nil {
+		v.add(path, "", "wrong json format: %v", err)
+		return
+	}
+
+	// a file contains only routes
+	if model.Name == "" && model.CustomRoutes != nil {
+		v.checkRoutes(path, model.CustomRoutes)
+		return
+	}
+
+	if model.Name == "" {
+		v.add(path, "/resource_name", "resource_name must be present")
+	} else if file, ok := v.files[model.Name]; ok {
+		v.add(path, "/resource_name", "resource %s has been defined in %s", model.Name, file)
+	} else {
+		v.models[model.Name] = model
+		v.files[model.Name] = path
+	}
+
+	if len(model.Columns) < 1 || model.Columns[0].Name != "id" || model.Columns[0].Type != number.Name() {
+		v.add(path, "/columns/0", "the first column must be id with number type")
+	}
+	for i, column := range model.Columns {
+		if err := column.CheckMeta(); err != nil {
+			v.add(path, jsonPointer("columns", i), "%v", err)
+		}
+	}
+	if err := model.CheckOwnerMeta(); err != nil {
+		v.add(path, "/owner_column", "%v", err)
+	}
+	if err := model.CheckPermissionsMeta(); err != nil {
+		v.add(path, "/permissions", "%v", err)
+	}
+
+	if model.Latency != nil {
+		if err := model.Latency.CheckMeta(); err != nil {
+			v.add(path, "/latency", "%v", err)
+		}
+	}
+	for i, fault := range model.Faults {
+		if err := fault.CheckMeta(); err != nil {
+			v.add(path, jsonPointer("faults", i), "%v", err)
+		}
+	}
+
+	for i, limit := range model.RateLimits {
+		if err := limit.CheckMeta(); err != nil {
+			v.add(path, jsonPointer("rate_limits", i), "%v", err)
+		}
+	}
+
+	if model.Transform != nil {
+		if err := model.CheckTransformMeta(); err != nil {
+			v.add(path, "/transform", "%v", err)
+		}
+	}
+	v.checkRoutes(path, model.CustomRoutes)
+
+	switch model.StoreType {
+	case "", memoryStore, fileStore:
+	default:
+		v.add(path, "/store", "unknown store: %s", model.StoreType)
+	}
+
+	if err := model.loadSeedsFile(filepath.Dir(path)); err != nil {
+		v.add(path, "/seeds_file", "%v", err)
+	}
+}
+
+// checkRoutes checks the meta of every CustomRoute
+func (v *validator) checkRoutes(path string, routes []*CustomRoute) {
+	for i, route := range routes {
+		if err := route.CheckMeta(); err != nil {
+			v.add(path, jsonPointer("routes", i), "%v", err)
+		}
+	}
+}
+
+// seedsFile returns the file contains seeds of the Model
+func (v *validator) seedsFile(model *Model) string {
+	if model.SeedsFile != "" {
+		return model.seedsFilePath(filepath.Dir(model.router.filePath))
+	}
+	return model.router.filePath
+}
+
+// seedPointer returns the JSON pointer of the seed in the seeds file
+func (v *validator) seedPointer(model *Model, tokens ...interface{}) string {
+	if model.SeedsFile != "" {
+		return jsonPointer(tokens...)
+	}
+	return jsonPointer(append([]interface{}{"seeds"}, tokens...)...)
+}
+
+// checkRelationships checks elements of has_many and has_one
+func (v *validator) checkRelationships(model *Model) {
+	path := model.router.filePath
+	used := map[string]bool{}
+	relationships := []struct {
+		key   string
+		names []string
+	}{{"has_many", model.HasMany}, {"has_one", model.HasOne}}
+
+	for _, relationship := range relationships {
+		key := relationship.key
+		for i, name := range relationship.names {
+			if used[name] {
+				v.add(path, jsonPointer(key, i), "%s has been used", name)
+			}
+			used[name] = true
+
+			if _, ok := v.models[plural(name)]; !ok {
+				v.add(path, jsonPointer(key, i), "use unknown resource %s", name)
+			}
+		}
+	}
+}
+
+// checkSeeds checks every seed of the Model
+func (v *validator) checkSeeds(model *Model) {
+	file := v.seedsFile(model)
+	ids := map[interface{}]int{}
+	uniqueValues := map[string]map[interface{}]int{}
+
+	for i, seed := range model.Seeds {
+		keys := []string{}
+		for key := range seed {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if key == deletedAtKey && !model.hasColumn(key) {
+				if err := model.checkDeletedAt(seed); err != nil {
+					v.add(file, v.seedPointer(model, i, key), "%v", err)
+				}
+			} else if !model.hasColumn(key) {
+				v.add(file, v.seedPointer(model, i, key), "unknown column %s", key)
+			}
+		}
+
+		for _, column := range model.Columns {
+			pointer := v.seedPointer(model, i, column.Name)
+			value, ok := seed[column.Name]
+			if !ok {
+				if !column.isAuto() {
+					v.add(file, v.seedPointer(model, i), "has no column %s", column.Name)
+				}
+				continue
+			}
+			if err := column.CheckValue(value, model); err != nil {
+				v.add(file, pointer, "%v", err)
+				continue
+			}
+
+			if column.Name == "id" {
+				if j, ok := ids[value]; ok {
+					v.add(file, pointer, "id %v is duplicate with seed %d", value, j)
+				} else {
+					ids[value] = i
+				}
+			} else if column.Unique && isIndexable(value) {
+				if uniqueValues[column.Name] == nil {
+					uniqueValues[column.Name] = map[interface{}]int{}
+				}
+				if j, ok := uniqueValues[column.Name][value]; ok {
+					v.add(file, pointer, "unique value %v is duplicate with seed %d", value, j)
+				} else {
+					uniqueValues[column.Name][value] = i
+				}
+			}
+
+			if strings.HasSuffix(column.Name, "_id") {
+				v.checkForeignKey(model, column, value, file, pointer)
+			}
+		}
+	}
+}
+
+// checkForeignKey checks if the resource with the foreign key exists
+func (v *validator) checkForeignKey(model *Model, column *Column, value interface{}, file, pointer string) {
+	resName := plural(strings.TrimSuffix(column.Name, "_id"))
+	related, ok := v.models[resName]
+	if !ok {
+		v.add(file, pointer, "use unknown resource %s", resName)
+		return
+	}
+
+	if v.ids[resName] == nil {
+		v.ids[resName] = map[interface{}]bool{}
+		for _, seed := range related.Seeds {
+			if id, ok := seed["id"]; ok && isIndexable(id) {
+				v.ids[resName][id] = true
+			}
+		}
+	}
+
+	if isIndexable(value) && v.ids[resName][value] {
+		return
+	}
+	v.add(file, pointer, "has no item[id=%v] of resource %s", value, resName)
+}
+
+// hasColumn returns if the Model has a Column with the given name
+func (model *Model) hasColumn(name string) bool {
+	for _, column := range model.Columns {
+		if column.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package apifaker
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	Describ("Validate", t, func() {
+		Context("when all files are valid", func() {
+			It("returns no problems", func() {
+				Expect(Validate(testDir), ShouldBeEmpty)
+			})
+		})
+
+		Context("when files have problems", func() {
+			dir, _ := ioutil.TempDir("", "apifaker")
+			defer os.RemoveAll(dir)
+			usersPath := filepath.Join(dir, "users.json")
+			booksPath := filepath.Join(dir, "books.json")
+			ioutil.WriteFile(usersPath, []byte(`{"resource_name": "users", "has_many": ["books", "foos"],
+				"columns": [{"name": "id", "type": "number"}, {"name": "name", "type": "xxx"}, {"name": "phone", "type": "string", "unique": true}],
+				"seeds": [{"id": 1, "name": "a", "phone": "1"}, {"id": 1, "name": "b", "phone": "1"}]}`), 0644)
+			ioutil.WriteFile(booksPath, []byte(`{"resource_name": "books",
+				"columns": [{"name": "id", "type": "number"}, {"name": "user_id", "type": "number"}],
+				"seeds": [{"id": 1, "user_id": 2}, {"id": 2, "user_id": "1", "title": "x"}]}`), 0644)
+
+			It("returns every problem with file path and json pointer", func() {
+				pointers := map[string]bool{}
+				for _, problem := range Validate(dir) {
+					pointers[problem.File+"#"+problem.Pointer] = true
+				}
+
+				Expect(pointers, ShouldResemble, map[string]bool{
+					// unknown column type
+					usersPath + "#/columns/1": true,
+					// unknown has_many target
+					usersPath + "#/has_many/1": true,
+					// wrong value of the column with unknown type
+					usersPath + "#/seeds/0/name": true,
+					usersPath + "#/seeds/1/name": true,
+					// duplicate id and unique value
+					usersPath + "#/seeds/1/id":    true,
+					usersPath + "#/seeds/1/phone": true,
+					// dangling foreign key
+					booksPath + "#/seeds/0/user_id": true,
+					// wrong type of foreign key
+					booksPath + "#/seeds/1/user_id": true,
+					// unknown column
+					booksPath + "#/seeds/1/title": true,
+				})
+			})
+		})
+
+		Context("when owner_column is wrong", func() {
+			dir, _ := ioutil.TempDir("", "apifaker")
+			defer os.RemoveAll(dir)
+			ioutil.WriteFile(filepath.Join(dir, "books.json"), []byte(`{"resource_name": "books", "owner_column": "title",
+				"columns": [{"name": "id", "type": "number"}, {"name": "title", "type": "string"}]}`), 0644)
+
+			It("returns a problem of owner_column", func() {
+				problems := Validate(dir)
+				Expect(len(problems), ShouldEqual, 1)
+				Expect(problems[0].Pointer, ShouldEqual, "/owner_column")
+			})
+		})
+
+		Context("when permissions are wrong", func() {
+			dir, _ := ioutil.TempDir("", "apifaker")
+			defer os.RemoveAll(dir)
+			ioutil.WriteFile(filepath.Join(dir, "books.json"), []byte(`{"resource_name": "books",
+				"columns": [{"name": "id", "type": "number"}, {"name": "title", "type": "string"}],
+				"permissions": {"member": {"methods": ["GET"], "read": ["price"]}}}`), 0644)
+			ioutil.WriteFile(filepath.Join(dir, "tags.json"), []byte(`{"resource_name": "tags",
+				"columns": [{"name": "id", "type": "number"}],
+				"permissions": {"member": {"methods": ["FETCH"]}}}`), 0644)
+
+			It("returns a problem of permissions for every file", func() {
+				problems := Validate(dir)
+				Expect(len(problems), ShouldEqual, 2)
+				Expect(problems[0].Pointer, ShouldEqual, "/permissions")
+				Expect(problems[1].Pointer, ShouldEqual, "/permissions")
+			})
+		})
+
+		Context("when a file has wrong json format", func() {
+			dir, _ := ioutil.TempDir("", "apifaker")
+			defer os.RemoveAll(dir)
+			ioutil.WriteFile(filepath.Join(dir, "users.json"), []byte(`{"resource_name": `), 0644)
+
+			It("returns a problem of the file", func() {
+				problems := Validate(dir)
+				Expect(len(problems), ShouldEqual, 1)
+				Expect(problems[0].File, ShouldEqual, filepath.Join(dir, "users.json"))
+			})
+		})
+	})
+}